@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yagihash/ghat/v2/internal/actions"
+	"github.com/yagihash/ghat/v2/internal/input"
+	"github.com/yagihash/ghat/v2/internal/jwt"
+)
+
+// runJWT signs a GitHub App JWT for App-level API calls and writes it to the
+// jwt output, or to stdout outside of GitHub Actions.
+func runJWT() int {
+	ctx := context.Background()
+
+	args, err := input.Load()
+	if err != nil {
+		actions.LogError("failed to load inputs: " + err.Error())
+		return exitErr
+	}
+
+	signer, err := newSigner(ctx, args)
+	if err != nil {
+		actions.LogError("failed to create signer: " + err.Error())
+		return exitErr
+	}
+	defer func(signer closableSigner) {
+		if err := signer.Close(); err != nil {
+			actions.LogWarning("failed to close signer: " + err.Error())
+		}
+	}(signer)
+
+	signedJWT, err := jwt.Build(ctx, signer, args.Issuer(), time.Now(), jwtOptions(args)...)
+	if err != nil {
+		actions.LogError("failed to build jwt: " + err.Error())
+		return exitErr
+	}
+
+	if isActions {
+		if err := exportSecret("jwt", signedJWT); err != nil {
+			actions.LogError(err.Error())
+			return exitErr
+		}
+	} else {
+		fmt.Print(signedJWT)
+	}
+
+	return exitOK
+}
+
+// timeClaims are the claims holding NumericDate values, in the order they are
+// printed.
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+// runJWTDecode prints the header and claims of the JWT in argv, or read from
+// stdin, followed by its time claims in a readable form.
+func runJWTDecode(argv []string) int {
+	var token string
+	if len(argv) == 1 && argv[0] != "-" {
+		token = argv[0]
+	} else {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to read jwt: "+err.Error())
+			return exitErr
+		}
+		token = string(b)
+	}
+
+	header, claims, err := jwt.Decode(token)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitErr
+	}
+
+	for _, part := range []struct {
+		name  string
+		value map[string]any
+	}{
+		{name: "header", value: header},
+		{name: "claims", value: claims},
+	} {
+		b, err := json.MarshalIndent(part.value, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return exitErr
+		}
+		fmt.Printf("%s:\n%s\n", part.name, b)
+	}
+
+	now := time.Now()
+	for _, name := range timeClaims {
+		n, ok := claims[name].(json.Number)
+		if !ok {
+			continue
+		}
+		sec, err := n.Int64()
+		if err != nil {
+			continue
+		}
+		fmt.Printf("%s: %s\n", name, formatTime(time.Unix(sec, 0), now))
+	}
+
+	return exitOK
+}
+
+// formatTime formats t in UTC along with its distance from now.
+func formatTime(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+
+	relative := "now"
+	switch {
+	case d > 0:
+		relative = "in " + d.String()
+	case d < 0:
+		relative = strings.TrimPrefix(d.String(), "-") + " ago"
+	}
+
+	return fmt.Sprintf("%s (%s)", t.UTC().Format(time.RFC3339), relative)
+}
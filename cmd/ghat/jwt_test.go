@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+
+	"github.com/yagihash/ghat/v2/internal/kms"
+	"github.com/yagihash/ghat/v2/internal/kms/kmstest"
+)
+
+func TestRealMain_JWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kmsSrv := kmstest.NewServer(key)
+	t.Cleanup(kmsSrv.Close)
+	kmsSrv.AddVersion("projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+
+	orig := isActions
+	isActions = false
+	t.Cleanup(func() { isActions = orig })
+
+	t.Setenv(kms.EndpointEnv, kmsSrv.Addr)
+	t.Setenv("INPUT_CLIENT_ID", "Iv23liABCDEF")
+	t.Setenv("INPUT_KMS_KEY", "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1")
+
+	var code int
+	out := captureStdout(t, func() { code = realMain([]string{"jwt"}) })
+
+	if code != exitOK {
+		t.Fatalf("realMain() = %d, want %d; output: %s", code, exitOK, out)
+	}
+	parts := strings.Split(out, ".")
+	if len(parts) != 3 {
+		t.Fatalf("output is not a JWT: %q", out)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestRealMain_JWTDecode(t *testing.T) {
+	// {"alg":"RS256","typ":"JWT"}.{"exp":1705320600,"iat":1705319940,"iss":"Iv23liABCDEF"}
+	token := "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9.eyJleHAiOjE3MDUzMjA2MDAsImlhdCI6MTcwNTMxOTk0MCwiaXNzIjoiSXYyM2xpQUJDREVGIn0.c2ln"
+
+	tests := []struct {
+		name     string
+		argv     []string
+		wantCode int
+		want     []string
+	}{
+		{
+			name:     "decodes token",
+			argv:     []string{"jwt", "decode", token},
+			wantCode: exitOK,
+			want: []string{
+				"header:\n{\n  \"alg\": \"RS256\",\n  \"typ\": \"JWT\"\n}\n",
+				"\"iss\": \"Iv23liABCDEF\"",
+				"iat: 2024-01-15T11:59:00Z (",
+				"exp: 2024-01-15T12:10:00Z (",
+			},
+		},
+		{
+			name:     "invalid token",
+			argv:     []string{"jwt", "decode", "not-a-jwt"},
+			wantCode: exitErr,
+		},
+		{
+			name:     "unknown command",
+			argv:     []string{"jwt", "encode"},
+			wantCode: exitErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			out := captureStdout(t, func() { code = realMain(tt.argv) })
+
+			if code != tt.wantCode {
+				t.Fatalf("realMain() = %d, want %d; output: %s", code, tt.wantCode, out)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output does not contain %q:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{t: now.Add(-time.Minute), want: "2024-01-15T11:59:00Z (1m0s ago)"},
+		{t: now.Add(10 * time.Minute), want: "2024-01-15T12:10:00Z (in 10m0s)"},
+		{t: now, want: "2024-01-15T12:00:00Z (now)"},
+	}
+
+	for _, tt := range tests {
+		if got := formatTime(tt.t, now); got != tt.want {
+			t.Errorf("formatTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/yagihash/ghat/v2/internal/actions"
 	"github.com/yagihash/ghat/v2/internal/client"
+	"github.com/yagihash/ghat/v2/internal/failover"
 	"github.com/yagihash/ghat/v2/internal/input"
 	"github.com/yagihash/ghat/v2/internal/jwt"
-	"github.com/yagihash/ghat/v2/internal/kms"
 )
 
 const (
@@ -20,11 +22,50 @@ const (
 
 var isActions = os.Getenv("GITHUB_ACTIONS") == "true"
 
+// logInfo writes an informational message to stderr, because stdout carries
+// the token outside of GitHub Actions.
+func logInfo(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// logWarning reports a recovered failure as a workflow warning, or on stderr
+// outside of GitHub Actions.
+func logWarning(msg string) {
+	if isActions {
+		actions.LogWarning(msg)
+		return
+	}
+	logInfo("warning: " + msg)
+}
+
+const usage = `usage:
+  ghat              issue an installation access token
+  ghat jwt          print a signed GitHub App JWT
+  ghat jwt decode [TOKEN]
+                    print the header and claims of TOKEN, or of the JWT read
+                    from stdin, without verifying it`
+
 func main() {
-	os.Exit(realMain())
+	os.Exit(realMain(os.Args[1:]))
+}
+
+func realMain(argv []string) int {
+	switch {
+	case len(argv) == 0:
+		return runToken()
+	case len(argv) == 1 && argv[0] == "jwt":
+		return runJWT()
+	case len(argv) >= 2 && len(argv) <= 3 && argv[0] == "jwt" && argv[1] == "decode":
+		return runJWTDecode(argv[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return exitErr
+	}
 }
 
-func realMain() int {
+// runToken issues an installation access token and writes it to the token
+// output, or to stdout outside of GitHub Actions.
+func runToken() int {
 	ctx := context.Background()
 
 	args, err := input.Load()
@@ -33,51 +74,148 @@ func realMain() int {
 		return exitErr
 	}
 
-	signer, err := kms.NewSigner(ctx, args.ProjectID, args.Location, args.KeyRingID, args.KeyID, args.KeyVersion)
+	signer, err := newSigner(ctx, args)
 	if err != nil {
 		actions.LogError("failed to create signer: " + err.Error())
 		return exitErr
 	}
-	defer func(signer *kms.Signer) {
+	defer func(signer closableSigner) {
 		if err := signer.Close(); err != nil {
-			actions.LogWarning("failed to close KMS signer: " + err.Error())
+			actions.LogWarning("failed to close signer: " + err.Error())
 		}
 	}(signer)
 
-	signedJWT, err := jwt.Build(ctx, signer, args.AppID, time.Now())
-	if err != nil {
-		actions.LogError("failed to build jwt: " + err.Error())
-		return exitErr
-	}
-
-	c := client.New(args.BaseURL, signedJWT)
-
-	installation, err := c.GetInstallationByOwner(args.Owner)
+	var (
+		signedJWT   string
+		accessToken *client.AccessTokenResponse
+	)
+	err = failover.Try(ctx, signer, func(s failover.Signer) error {
+		var err error
+		signedJWT, accessToken, err = issueToken(ctx, s, args)
+		if err != nil && failover.Retryable(err) {
+			logWarning(fmt.Sprintf("key %s failed: %v", s.KeyID(), err))
+		}
+		return err
+	})
 	if err != nil {
-		actions.LogError("failed to get installation: " + err.Error())
+		actions.LogError(err.Error())
 		return exitErr
 	}
 
-	accessToken, err := c.GetInstallationAccessToken(installation.ID, args.Permissions, args.Repositories)
-	if err != nil {
-		actions.LogError("failed to get access token: " + err.Error())
-		return exitErr
-	}
 	if isActions {
-		actions.AddMask(accessToken.Token)
-
-		if err := actions.SetState("token", accessToken.Token); err != nil {
+		if err := exportSecret("token", accessToken.Token); err != nil {
 			actions.LogError(err.Error())
 			return exitErr
 		}
 
-		if err := actions.SetOutput("token", accessToken.Token); err != nil {
+		if err := exportTokenDetails(accessToken); err != nil {
 			actions.LogError(err.Error())
 			return exitErr
 		}
+
+		if args.OutputJWT {
+			if err := exportSecret("jwt", signedJWT); err != nil {
+				actions.LogError(err.Error())
+				return exitErr
+			}
+		}
 	} else {
 		fmt.Print(accessToken.Token)
 	}
 
 	return exitOK
 }
+
+// exportSecret masks value and stores it in the state and the output named key.
+func exportSecret(key, value string) error {
+	actions.AddMask(value)
+
+	if err := actions.SetState(key, value); err != nil {
+		return err
+	}
+
+	return actions.SetOutput(key, value)
+}
+
+// exportTokenDetails sets the expires_at, permissions and repositories
+// outputs from accessToken, the latter two as JSON.
+func exportTokenDetails(accessToken *client.AccessTokenResponse) error {
+	permissions := accessToken.Permissions
+	if permissions == nil {
+		permissions = map[string]string{}
+	}
+	permissionsJSON, err := json.Marshal(permissions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permissions: %w", err)
+	}
+
+	repositories := accessToken.Repositories
+	if repositories == nil {
+		repositories = []client.Repository{}
+	}
+	repositoriesJSON, err := json.Marshal(repositories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal repositories: %w", err)
+	}
+
+	if err := actions.SetOutput("expires_at", accessToken.ExpiresAt.Format(time.RFC3339)); err != nil {
+		return err
+	}
+	if err := actions.SetOutput("permissions", string(permissionsJSON)); err != nil {
+		return err
+	}
+	return actions.SetOutput("repositories", string(repositoriesJSON))
+}
+
+// issueToken requests an installation access token with a JWT signed by
+// signer. When GitHub rejects the JWT because the local clock is off, it is
+// built again with GitHub's time and the request retried once.
+func issueToken(ctx context.Context, signer failover.Signer, args *input.Config) (string, *client.AccessTokenResponse, error) {
+	signedJWT, accessToken, err := requestToken(ctx, signer, args, time.Now())
+
+	var skew *client.ClockSkewError
+	if errors.As(err, &skew) {
+		logWarning(fmt.Sprintf("GitHub rejected the jwt because the local clock is off by %v, retrying with GitHub's time", -skew.Offset.Round(time.Second)))
+		return requestToken(ctx, signer, args, time.Now().Add(skew.Offset))
+	}
+
+	return signedJWT, accessToken, err
+}
+
+// requestToken requests an installation access token with a JWT signed by
+// signer at now, and returns the JWT along with the token.
+func requestToken(ctx context.Context, signer failover.Signer, args *input.Config, now time.Time) (string, *client.AccessTokenResponse, error) {
+	signedJWT, err := jwt.Build(ctx, signer, args.Issuer(), now, jwtOptions(args)...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build jwt: %w", err)
+	}
+
+	c := client.New(args.BaseURL, signedJWT)
+	if args.HTTPTimeout != 0 {
+		c.HTTPClient.Timeout = time.Duration(args.HTTPTimeout)
+	}
+
+	installation, err := c.GetInstallationByOwner(ctx, args.Owner)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get installation: %w", err)
+	}
+
+	accessToken, err := c.GetInstallationAccessToken(ctx, installation.ID, args.Permissions, args.Repositories, args.RepositoryIDs)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	return signedJWT, accessToken, nil
+}
+
+// jwtOptions returns the jwt.Build options for the non-zero JWT inputs.
+func jwtOptions(args *input.Config) []jwt.Option {
+	var opts []jwt.Option
+	if args.JWTExpiry != 0 {
+		opts = append(opts, jwt.WithExpiry(time.Duration(args.JWTExpiry)))
+	}
+	if args.JWTIssuedAtSkew != 0 {
+		opts = append(opts, jwt.WithIssuedAtSkew(time.Duration(args.JWTIssuedAtSkew)))
+	}
+	return opts
+}
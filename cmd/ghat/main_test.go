@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yagihash/ghat/v2/internal/actions"
+	"github.com/yagihash/ghat/v2/internal/client"
+	"github.com/yagihash/ghat/v2/internal/kms"
+	"github.com/yagihash/ghat/v2/internal/kms/kmstest"
+)
+
+// fakeGitHub serves the installation and access token endpoints, accepting
+// only JWTs signed by pub.
+func fakeGitHub(t *testing.T, pub *rsa.PublicKey) *httptest.Server {
+	t.Helper()
+
+	verify := func(r *http.Request) bool {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			return false
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/octocat/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 42})
+	})
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if !verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": "ghs_e2e"})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	f()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// TestRealMain_KMSEmulator runs ghat end to end against the in-process KMS
+// fake and a fake GitHub API.
+func TestRealMain_KMSEmulator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kmsSrv := kmstest.NewServer(key)
+	t.Cleanup(kmsSrv.Close)
+	kmsSrv.AddVersion("projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+
+	github := fakeGitHub(t, &key.PublicKey)
+
+	orig := isActions
+	isActions = false
+	t.Cleanup(func() { isActions = orig })
+
+	t.Setenv(kms.EndpointEnv, kmsSrv.Addr)
+	t.Setenv("INPUT_APP_ID", "12345")
+	t.Setenv("INPUT_OWNER", "octocat")
+	t.Setenv("INPUT_BASE_URL", github.URL)
+	t.Setenv("INPUT_KMS_KEY", "projects/p/locations/global/keyRings/r/cryptoKeys/k")
+
+	var code int
+	out := captureStdout(t, func() { code = realMain(nil) })
+
+	if code != exitOK {
+		t.Fatalf("realMain() = %d, want %d; output: %s", code, exitOK, out)
+	}
+	if out != "ghs_e2e" {
+		t.Errorf("output = %q, want %q", out, "ghs_e2e")
+	}
+	if got := kmsSrv.SignCount(); got != 1 {
+		t.Errorf("SignCount() = %d, want 1", got)
+	}
+}
+
+// TestRealMain_KMSFallbackKeys checks that ghat signs with a fallback key when
+// the KMS key is unavailable.
+func TestRealMain_KMSFallbackKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kmsSrv := kmstest.NewServer(key)
+	t.Cleanup(kmsSrv.Close)
+	kmsSrv.AddVersion("projects/p/locations/us-central1/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+	kmsSrv.AddVersion("projects/p/locations/us-east1/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+	kmsSrv.FailSign(status.Error(codes.Unavailable, "us-central1 is unavailable"))
+
+	github := fakeGitHub(t, &key.PublicKey)
+
+	orig := isActions
+	isActions = false
+	t.Cleanup(func() { isActions = orig })
+
+	t.Setenv(kms.EndpointEnv, kmsSrv.Addr)
+	t.Setenv("INPUT_APP_ID", "12345")
+	t.Setenv("INPUT_OWNER", "octocat")
+	t.Setenv("INPUT_BASE_URL", github.URL)
+	t.Setenv("INPUT_KMS_KEY", "projects/p/locations/us-central1/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1")
+	t.Setenv("INPUT_KMS_FALLBACK_KEYS", "projects/p/locations/us-east1/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1")
+	// Without retries, the second AsymmetricSign call can only be made with
+	// the fallback key.
+	t.Setenv("INPUT_KMS_MAX_ATTEMPTS", "1")
+
+	var code int
+	out := captureStdout(t, func() { code = realMain(nil) })
+
+	if code != exitOK {
+		t.Fatalf("realMain() = %d, want %d; output: %s", code, exitOK, out)
+	}
+	if out != "ghs_e2e" {
+		t.Errorf("output = %q, want %q", out, "ghs_e2e")
+	}
+	if got := kmsSrv.SignCount(); got != 2 {
+		t.Errorf("SignCount() = %d, want 2", got)
+	}
+}
+
+func TestExportTokenDetails(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken *client.AccessTokenResponse
+		want        string
+	}{
+		{
+			name: "selected repositories",
+			accessToken: &client.AccessTokenResponse{
+				Token:               "ghs_xxx",
+				ExpiresAt:           time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				Permissions:         map[string]string{"contents": "read", "metadata": "read"},
+				RepositorySelection: "selected",
+				Repositories:        []client.Repository{{ID: 1296269, Name: "Hello-World", FullName: "octocat/Hello-World"}},
+			},
+			want: "expires_at=2026-10-18T12:00:00Z\n" +
+				`permissions={"contents":"read","metadata":"read"}` + "\n" +
+				`repositories=[{"id":1296269,"name":"Hello-World","full_name":"octocat/Hello-World"}]` + "\n",
+		},
+		{
+			name: "all repositories",
+			accessToken: &client.AccessTokenResponse{
+				Token:               "ghs_xxx",
+				ExpiresAt:           time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				RepositorySelection: "all",
+			},
+			want: "expires_at=2026-10-18T12:00:00Z\npermissions={}\nrepositories=[]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "output")
+			t.Setenv(actions.EnvGitHubOutput, output)
+
+			if err := exportTokenDetails(tt.accessToken); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
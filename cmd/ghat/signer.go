@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/auth"
+	"google.golang.org/api/option"
+
+	"github.com/yagihash/ghat/v2/internal/awskms"
+	"github.com/yagihash/ghat/v2/internal/azurekv"
+	"github.com/yagihash/ghat/v2/internal/failover"
+	"github.com/yagihash/ghat/v2/internal/input"
+	"github.com/yagihash/ghat/v2/internal/kms"
+	"github.com/yagihash/ghat/v2/internal/pkcs11"
+	"github.com/yagihash/ghat/v2/internal/privatekey"
+	"github.com/yagihash/ghat/v2/internal/sshagent"
+	"github.com/yagihash/ghat/v2/internal/vault"
+	"github.com/yagihash/ghat/v2/internal/wif"
+)
+
+// closableSigner is a signer that may hold resources which must be released.
+type closableSigner interface {
+	failover.Signer
+	Close() error
+}
+
+// newSigner creates the signer selected by args.Signer.
+func newSigner(ctx context.Context, args *input.Config) (closableSigner, error) {
+	switch args.Signer {
+	case input.SignerKMS:
+		return newKMSSigner(ctx, args)
+	case input.SignerPrivateKey:
+		return newPrivateKeySigner(args)
+	case input.SignerAWSKMS:
+		return awskms.NewSigner(ctx, args.AWSKMSKeyID, args.AWSKMSRegion)
+	case input.SignerVault:
+		return vault.NewSigner(ctx, vault.Config{
+			Address:      args.VaultAddress,
+			Namespace:    args.VaultNamespace,
+			Token:        args.VaultToken,
+			RoleID:       args.VaultRoleID,
+			SecretID:     args.VaultSecretID,
+			AppRoleMount: args.VaultAppRoleMount,
+			TransitMount: args.VaultTransitMount,
+			KeyName:      args.VaultTransitKey,
+			KeyVersion:   int(args.VaultTransitKeyVersion),
+		})
+	case input.SignerAzureKV:
+		return azurekv.NewSigner(args.AzureKeyVaultURL, args.AzureKeyName, args.AzureKeyVersion)
+	case input.SignerPKCS11:
+		return newPKCS11Signer(args)
+	case input.SignerSSHAgent:
+		return sshagent.NewSigner(args.SSHAuthSock, args.SSHAgentKeyFingerprint)
+	default:
+		return nil, fmt.Errorf("unknown signer: %q", args.Signer)
+	}
+}
+
+func newKMSSigner(ctx context.Context, args *input.Config) (closableSigner, error) {
+	name := kms.KeyName{
+		ProjectID: args.ProjectID,
+		Location:  args.Location,
+		KeyRingID: args.KeyRingID,
+		KeyID:     args.KeyID,
+		Version:   args.KeyVersion,
+	}
+	if args.KMSKey != "" {
+		n, err := kms.ParseKeyName(args.KMSKey)
+		if err != nil {
+			return nil, err
+		}
+		if n.Version == "" {
+			n.Version = kms.LatestVersion
+		}
+		name = n
+	}
+
+	creds, err := newGoogleCredentials(args)
+	if err != nil {
+		return nil, err
+	}
+	var opts []option.ClientOption
+	if creds != nil {
+		opts = append(opts, option.WithAuthCredentials(creds))
+	}
+
+	s, err := kms.NewSigner(ctx, name.ProjectID, name.Location, name.KeyRingID, name.KeyID, name.Version, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := kms.RetryConfig{
+		MaxAttempts: int(args.KMSMaxAttempts),
+		Timeout:     time.Duration(args.KMSTimeout),
+	}
+	s.SetRetryConfig(retry)
+	warnUnverified(s)
+
+	if name.Version == kms.LatestVersion {
+		logInfo("using kms key version " + s.Version())
+	}
+
+	if len(args.KMSFallbackKeys) == 0 {
+		return s, nil
+	}
+
+	signers := []failover.Signer{s}
+	for _, key := range args.KMSFallbackKeys {
+		fallback, err := kms.NewSignerFromResourceName(ctx, key, opts...)
+		if err != nil {
+			c, _ := failover.New(signers...)
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to create signer for fallback key %s: %w", key, err)
+		}
+		fallback.SetRetryConfig(retry)
+		warnUnverified(fallback)
+		signers = append(signers, fallback)
+	}
+
+	return failover.New(signers...)
+}
+
+// warnUnverified warns when the public key of s could not be read, so that
+// its algorithm is not checked and its signatures are not verified.
+func warnUnverified(s *kms.Signer) {
+	if err := s.Unverified(); err != nil {
+		logWarning(fmt.Sprintf("signatures by %s are not verified: %v; grant roles/cloudkms.signerVerifier instead of roles/cloudkms.signer to verify them", s.KeyID(), err))
+	}
+}
+
+// newGoogleCredentials returns the credentials for the kms signer, or nil to
+// use Application Default Credentials.
+func newGoogleCredentials(args *input.Config) (*auth.Credentials, error) {
+	var creds *auth.Credentials
+	if args.WorkloadIdentityProvider != "" {
+		c, err := wif.NewCredentials(wif.Config{
+			WorkloadIdentityProvider: args.WorkloadIdentityProvider,
+			ServiceAccount:           args.ServiceAccount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		creds = c
+	}
+
+	if args.ImpersonateServiceAccount != "" {
+		c, err := wif.Impersonate(wif.ImpersonateConfig{
+			Base:            creds,
+			TargetPrincipal: args.ImpersonateServiceAccount,
+			Delegates:       args.ImpersonateDelegates,
+		})
+		if err != nil {
+			return nil, err
+		}
+		creds = c
+	}
+
+	return creds, nil
+}
+
+func newPKCS11Signer(args *input.Config) (closableSigner, error) {
+	cfg := pkcs11.Config{
+		ModulePath: args.PKCS11Module,
+		TokenLabel: args.PKCS11TokenLabel,
+		PIN:        args.PKCS11PIN,
+		KeyLabel:   args.PKCS11KeyLabel,
+	}
+
+	if args.PKCS11Slot != "" {
+		slot, err := strconv.ParseUint(args.PKCS11Slot, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkcs11 slot %q: %w", args.PKCS11Slot, err)
+		}
+		id := uint(slot)
+		cfg.SlotID = &id
+	}
+
+	return pkcs11.NewSigner(cfg)
+}
+
+func newPrivateKeySigner(args *input.Config) (closableSigner, error) {
+	passphrase := []byte(args.PrivateKeyPassphrase)
+
+	switch args.PrivateKeyPath {
+	case "":
+		return privatekey.NewSigner([]byte(args.PrivateKey), passphrase)
+	case "-":
+		return privatekey.NewSignerFromReader(os.Stdin, passphrase)
+	default:
+		return privatekey.NewSignerFromFile(args.PrivateKeyPath, passphrase)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/yagihash/ghat/v2/internal/actions"
 	"github.com/yagihash/ghat/v2/internal/client"
@@ -31,7 +33,10 @@ func realMain() int {
 	}
 
 	c := client.New(args.BaseURL, token)
-	if err := c.DeleteInstallationAccessToken(); err != nil {
+	if args.HTTPTimeout != 0 {
+		c.HTTPClient.Timeout = time.Duration(args.HTTPTimeout)
+	}
+	if err := c.DeleteInstallationAccessToken(context.Background()); err != nil {
 		actions.LogError(err.Error())
 		return exitErr
 	}
@@ -0,0 +1,128 @@
+package awskms
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// signingAlgorithm is the AWS KMS equivalent of the RS256 JWS algorithm.
+const signingAlgorithm = types.SigningAlgorithmSpecRsassaPkcs1V15Sha256
+
+// supportedKeySpecs lists the key specs that can produce RS256 signatures.
+var supportedKeySpecs = []types.KeySpec{
+	types.KeySpecRsa2048,
+	types.KeySpecRsa3072,
+	types.KeySpecRsa4096,
+}
+
+// KMSClient defines the interface for AWS KMS operations
+type KMSClient interface {
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+}
+
+type Signer struct {
+	client KMSClient
+	keyID  string
+}
+
+// NewKMSClient creates a real AWS KMS client using the default credential chain.
+// region overrides the region from the environment when it is not empty.
+func NewKMSClient(ctx context.Context, region string) (KMSClient, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	return kms.NewFromConfig(cfg), nil
+}
+
+// NewSigner creates a new Signer with an AWS KMS client.
+// keyID is a key ID, key ARN, alias name, or alias ARN.
+func NewSigner(ctx context.Context, keyID, region string) (*Signer, error) {
+	client, err := NewKMSClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigner(ctx, client, keyID)
+}
+
+// newSigner creates a new Signer with the given KMS client, after checking
+// that the key can produce RS256 signatures.
+func newSigner(ctx context.Context, client KMSClient, keyID string) (*Signer, error) {
+	out, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{
+		KeyId: aws.String(keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key: %w", err)
+	}
+
+	if out.KeyUsage != types.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("key %s has usage %s, want %s", keyID, out.KeyUsage, types.KeyUsageTypeSignVerify)
+	}
+
+	if !slices.Contains(supportedKeySpecs, out.KeySpec) {
+		return nil, fmt.Errorf("key %s has spec %s, want one of %v", keyID, out.KeySpec, supportedKeySpecs)
+	}
+
+	if !slices.Contains(out.SigningAlgorithms, signingAlgorithm) {
+		return nil, fmt.Errorf("key %s does not support %s", keyID, signingAlgorithm)
+	}
+
+	// Prefer the resolved key ARN so that aliases are pinned to the key
+	// that was validated.
+	resolved := keyID
+	if out.KeyId != nil && *out.KeyId != "" {
+		resolved = *out.KeyId
+	}
+
+	return &Signer{
+		client: client,
+		keyID:  resolved,
+	}, nil
+}
+
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	out, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          digest[:],
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: signingAlgorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return out.Signature, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// KeyID returns the ARN of the KMS key used for signing.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Close is a no-op. The AWS SDK client does not hold a connection that needs
+// to be released.
+func (s *Signer) Close() error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package awskms
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+const testKeyARN = "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+// fakeKMSClient is a local stand-in for AWS KMS holding a real RSA key.
+type fakeKMSClient struct {
+	key          *rsa.PrivateKey
+	publicKeyOut *kms.GetPublicKeyOutput
+	publicKeyErr error
+	signErr      error
+	signInput    *kms.SignInput
+}
+
+func newFakeKMSClient(t *testing.T) *fakeKMSClient {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeKMSClient{
+		key: key,
+		publicKeyOut: &kms.GetPublicKeyOutput{
+			KeyId:             aws.String(testKeyARN),
+			KeySpec:           types.KeySpecRsa2048,
+			KeyUsage:          types.KeyUsageTypeSignVerify,
+			SigningAlgorithms: []types.SigningAlgorithmSpec{types.SigningAlgorithmSpecRsassaPssSha256, types.SigningAlgorithmSpecRsassaPkcs1V15Sha256},
+		},
+	}
+}
+
+func (f *fakeKMSClient) Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+	f.signInput = params
+	if f.signErr != nil {
+		return nil, f.signErr
+	}
+	sig, err := rsa.SignPKCS1v15(nil, f.key, crypto.SHA256, params.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{KeyId: params.KeyId, Signature: sig, SigningAlgorithm: params.SigningAlgorithm}, nil
+}
+
+func (f *fakeKMSClient) GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	if f.publicKeyErr != nil {
+		return nil, f.publicKeyErr
+	}
+	return f.publicKeyOut, nil
+}
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyID     string
+		modify    func(f *fakeKMSClient)
+		wantKeyID string
+		wantErr   bool
+	}{
+		{
+			name:      "resolves alias to key ARN",
+			keyID:     "alias/github-app",
+			wantKeyID: testKeyARN,
+		},
+		{
+			name:  "accepts RSA_4096",
+			keyID: testKeyARN,
+			modify: func(f *fakeKMSClient) {
+				f.publicKeyOut.KeySpec = types.KeySpecRsa4096
+			},
+			wantKeyID: testKeyARN,
+		},
+		{
+			name:  "GetPublicKey error",
+			keyID: testKeyARN,
+			modify: func(f *fakeKMSClient) {
+				f.publicKeyErr = errors.New("AccessDeniedException")
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects encryption keys",
+			keyID: testKeyARN,
+			modify: func(f *fakeKMSClient) {
+				f.publicKeyOut.KeyUsage = types.KeyUsageTypeEncryptDecrypt
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects EC keys",
+			keyID: testKeyARN,
+			modify: func(f *fakeKMSClient) {
+				f.publicKeyOut.KeySpec = types.KeySpecEccNistP256
+			},
+			wantErr: true,
+		},
+		{
+			name:  "rejects keys without PKCS#1 v1.5 SHA-256",
+			keyID: testKeyARN,
+			modify: func(f *fakeKMSClient) {
+				f.publicKeyOut.SigningAlgorithms = []types.SigningAlgorithmSpec{types.SigningAlgorithmSpecRsassaPssSha256}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeKMSClient(t)
+			if tt.modify != nil {
+				tt.modify(client)
+			}
+
+			signer, err := newSigner(context.Background(), client, tt.keyID)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := signer.KeyID(); got != tt.wantKeyID {
+				t.Errorf("KeyID() = %v, want %v", got, tt.wantKeyID)
+			}
+		})
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	client := newFakeKMSClient(t)
+	signer, err := newSigner(context.Background(), client, "alias/github-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("header.payload")
+	sig, err := signer.Sign(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(&client.key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	in := client.signInput
+	if got := aws.ToString(in.KeyId); got != testKeyARN {
+		t.Errorf("KeyId = %v, want %v", got, testKeyARN)
+	}
+	if in.MessageType != types.MessageTypeDigest {
+		t.Errorf("MessageType = %v, want %v", in.MessageType, types.MessageTypeDigest)
+	}
+	if in.SigningAlgorithm != types.SigningAlgorithmSpecRsassaPkcs1V15Sha256 {
+		t.Errorf("SigningAlgorithm = %v, want %v", in.SigningAlgorithm, types.SigningAlgorithmSpecRsassaPkcs1V15Sha256)
+	}
+}
+
+func TestSigner_Sign_Error(t *testing.T) {
+	client := newFakeKMSClient(t)
+	signer, err := newSigner(context.Background(), client, testKeyARN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.signErr = errors.New("KMSInvalidStateException")
+	if _, err := signer.Sign(context.Background(), []byte("data")); err == nil {
+		t.Error("expected error but got nil")
+	}
+}
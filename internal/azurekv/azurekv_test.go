@@ -0,0 +1,188 @@
+package azurekv
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+type fakeCredential struct {
+	err    error
+	scopes []string
+}
+
+func (f *fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	f.scopes = opts.Scopes
+	if f.err != nil {
+		return azcore.AccessToken{}, f.err
+	}
+	return azcore.AccessToken{Token: "test-access-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func jsonResponse(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = io.WriteString(w, body)
+}
+
+// fakeKeyVault is an HTTP-level stand-in for the Key Vault sign operation.
+func fakeKeyVault(t *testing.T, key *rsa.PrivateKey, wantPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			jsonResponse(w, http.StatusUnauthorized, `{"error":{"code":"Unauthorized","message":"AKV10000: Request is missing a Bearer or PoP token."}}`)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != wantPath || r.URL.Query().Get("api-version") != apiVersion {
+			jsonResponse(w, http.StatusNotFound, `{"error":{"code":"KeyNotFound","message":"A key with (name/id) was not found in this key vault."}}`)
+			return
+		}
+
+		var req signRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Algorithm != "RS256" {
+			jsonResponse(w, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"invalid request"}}`)
+			return
+		}
+		digest, err := base64.RawURLEncoding.DecodeString(req.Value)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, `{"error":{"code":"BadParameter","message":"invalid value"}}`)
+			return
+		}
+		sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest)
+		if err != nil {
+			t.Error(err)
+			jsonResponse(w, http.StatusInternalServerError, `{"error":{"code":"InternalServerError","message":"failed to sign"}}`)
+			return
+		}
+		jsonResponse(w, http.StatusOK, `{"kid":"https://my-vault.vault.azure.net/keys/github-app/abc123","value":"`+base64.RawURLEncoding.EncodeToString(sig)+`"}`)
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		keyName    string
+		keyVersion string
+		wantPath   string
+		credErr    error
+		wantErr    bool
+	}{
+		{
+			name:       "pinned version",
+			keyName:    "github-app",
+			keyVersion: "abc123",
+			wantPath:   "/keys/github-app/abc123/sign",
+		},
+		{
+			name:     "current version",
+			keyName:  "github-app",
+			wantPath: "/keys/github-app//sign",
+		},
+		{
+			name:     "key not found",
+			keyName:  "other",
+			wantPath: "/keys/github-app//sign",
+			wantErr:  true,
+		},
+		{
+			name:     "credential error",
+			keyName:  "github-app",
+			wantPath: "/keys/github-app//sign",
+			credErr:  errors.New("DefaultAzureCredential: failed to acquire a token"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(fakeKeyVault(t, key, tt.wantPath))
+			defer srv.Close()
+
+			cred := &fakeCredential{err: tt.credErr}
+			s, err := newSigner(cred, srv.URL, tt.keyName, tt.keyVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := []byte("header.payload")
+			sig, err := s.Sign(context.Background(), data)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			digest := sha256.Sum256(data)
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestScopeFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		vaultURL string
+		want     string
+		wantErr  bool
+	}{
+		{name: "public cloud", vaultURL: "https://my-vault.vault.azure.net", want: "https://vault.azure.net/.default"},
+		{name: "trailing slash", vaultURL: "https://my-vault.vault.azure.net/", want: "https://vault.azure.net/.default"},
+		{name: "china cloud", vaultURL: "https://my-vault.vault.azure.cn", want: "https://vault.azure.cn/.default"},
+		{name: "managed HSM", vaultURL: "https://my-hsm.managedhsm.azure.net", want: "https://managedhsm.azure.net/.default"},
+		{name: "missing scheme", vaultURL: "my-vault.vault.azure.net", wantErr: true},
+		{name: "bare host", vaultURL: "https://localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scopeFor(tt.vaultURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("scopeFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSigner_KeyID(t *testing.T) {
+	s, err := newSigner(&fakeCredential{}, "https://my-vault.vault.azure.net/", "github-app", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://my-vault.vault.azure.net/keys/github-app/abc123"
+	if got := s.KeyID(); got != want {
+		t.Errorf("KeyID() = %q, want %q", got, want)
+	}
+}
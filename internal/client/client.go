@@ -2,17 +2,45 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"time"
 )
 
+// ClockSkewError is returned for 401 responses that reject the JWT because of
+// its iat or exp claim, when the response has a Date header. Offset is the
+// time of GitHub's clock minus the local one, to be added to the time the JWT
+// is built with.
+type ClockSkewError struct {
+	Offset time.Duration
+	Err    error
+}
+
+func (e *ClockSkewError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClockSkewError) Unwrap() error {
+	return e.Err
+}
+
+// timingClaims appear in the messages of 401 responses that reject the iat or
+// exp claim, e.g. "'Expiration time' claim ('exp') is too far in the future".
+var timingClaims = []string{"('iat')", "('exp')"}
+
+// DefaultTimeout is the timeout of each request made by a Client from New.
+const DefaultTimeout = 10 * time.Second
+
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
-	token      string
+	// Retry is the retry policy of all requests.
+	Retry RetryPolicy
+	token string
 }
 
 type InstallationResponse struct {
@@ -20,12 +48,30 @@ type InstallationResponse struct {
 }
 
 type AccessTokenRequest struct {
-	Repositories []string          `json:"repositories,omitempty"`
-	Permissions  map[string]string `json:"permissions,omitempty"`
+	Repositories  []string          `json:"repositories,omitempty"`
+	RepositoryIDs []int64           `json:"repository_ids,omitempty"`
+	Permissions   map[string]string `json:"permissions,omitempty"`
 }
 
 type AccessTokenResponse struct {
-	Token string `json:"token"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Permissions are the permissions granted to the token, which are those
+	// of the installation when none were requested.
+	Permissions map[string]string `json:"permissions"`
+	// RepositorySelection is "all" or "selected".
+	RepositorySelection string `json:"repository_selection"`
+	// Repositories are the repositories the token is scoped to. It is empty
+	// when RepositorySelection is "all".
+	Repositories []Repository `json:"repositories"`
+}
+
+// Repository is the part of a repository in an AccessTokenResponse used by
+// ghat.
+type Repository struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
 }
 
 func New(baseURL, jwt string) *Client {
@@ -33,12 +79,13 @@ func New(baseURL, jwt string) *Client {
 		BaseURL: baseURL,
 		token:   jwt,
 		HTTPClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: DefaultTimeout,
 		},
+		Retry: DefaultRetryPolicy,
 	}
 }
 
-func (c *Client) newRequest(method, path string, body any) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, path)
 
 	var bodyReader io.Reader
@@ -50,7 +97,7 @@ func (c *Client) newRequest(method, path string, body any) (*http.Request, error
 		bodyReader = bytes.NewBuffer(jsonBytes)
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -65,14 +112,14 @@ func (c *Client) newRequest(method, path string, body any) (*http.Request, error
 	return req, nil
 }
 
-func (c *Client) GetInstallationByOwner(owner string) (*InstallationResponse, error) {
+func (c *Client) GetInstallationByOwner(ctx context.Context, owner string) (*InstallationResponse, error) {
 	path := fmt.Sprintf("users/%s/installation", owner)
-	req, err := c.newRequest(http.MethodGet, path, nil)
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +127,7 @@ func (c *Client) GetInstallationByOwner(owner string) (*InstallationResponse, er
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get installation: %s, body: %s", resp.Status, string(body))
+		return nil, checkClockSkew(resp, body, fmt.Errorf("failed to get installation: %w", newAPIError(resp, body)))
 	}
 
 	var installation InstallationResponse
@@ -91,20 +138,21 @@ func (c *Client) GetInstallationByOwner(owner string) (*InstallationResponse, er
 	return &installation, nil
 }
 
-func (c *Client) GetInstallationAccessToken(installationID int64, permissions map[string]string, repos []string) (*AccessTokenResponse, error) {
+func (c *Client) GetInstallationAccessToken(ctx context.Context, installationID int64, permissions map[string]string, repos []string, repoIDs []int64) (*AccessTokenResponse, error) {
 	path := fmt.Sprintf("app/installations/%d/access_tokens", installationID)
 
 	payload := AccessTokenRequest{
-		Repositories: repos,
-		Permissions:  permissions,
+		Repositories:  repos,
+		RepositoryIDs: repoIDs,
+		Permissions:   permissions,
 	}
 
-	req, err := c.newRequest(http.MethodPost, path, payload)
+	req, err := c.newRequest(ctx, http.MethodPost, path, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -113,9 +161,9 @@ func (c *Client) GetInstallationAccessToken(installationID int64, permissions ma
 	if resp.StatusCode != http.StatusCreated {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get token: %s (failed to read body: %w)", resp.Status, err)
+			return nil, fmt.Errorf("failed to get token: %w (failed to read body: %w)", newAPIError(resp, nil), err)
 		}
-		return nil, fmt.Errorf("failed to get token: %s, body: %s", resp.Status, string(body))
+		return nil, checkClockSkew(resp, body, fmt.Errorf("failed to get token: %w", newAPIError(resp, body)))
 	}
 
 	var tokenResp AccessTokenResponse
@@ -126,23 +174,46 @@ func (c *Client) GetInstallationAccessToken(installationID int64, permissions ma
 	return &tokenResp, nil
 }
 
-func (c *Client) DeleteInstallationAccessToken() error {
+func (c *Client) DeleteInstallationAccessToken(ctx context.Context) error {
 	path := "installation/token"
 
-	req, err := c.newRequest(http.MethodDelete, path, nil)
+	req, err := c.newRequest(ctx, http.MethodDelete, path, nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("failed to delete token: %s", resp.Status)
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete token: %w", newAPIError(resp, body))
 	}
 
 	return nil
 }
+
+// checkClockSkew returns err as a *ClockSkewError if resp rejects the JWT for
+// its timing claims and tells GitHub's time, and err unchanged otherwise.
+func checkClockSkew(resp *http.Response, body []byte, err error) error {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+
+	timing := slices.ContainsFunc(timingClaims, func(claim string) bool {
+		return bytes.Contains(body, []byte(claim))
+	})
+	if !timing {
+		return err
+	}
+
+	date, dateErr := http.ParseTime(resp.Header.Get("Date"))
+	if dateErr != nil {
+		return err
+	}
+
+	return &ClockSkewError{Offset: time.Until(date), Err: err}
+}
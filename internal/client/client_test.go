@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -29,6 +30,7 @@ func newResponse(statusCode int, body string) *http.Response {
 func newClientWithMock(baseURL, jwt string, transport http.RoundTripper) *Client {
 	c := New(baseURL, jwt)
 	c.HTTPClient.Transport = transport
+	c.Retry = RetryPolicy{Initial: time.Millisecond, Max: time.Millisecond}
 	return c
 }
 
@@ -132,7 +134,7 @@ func TestGetInstallationByOwner(t *testing.T) {
 			}
 			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
 
-			got, err := c.GetInstallationByOwner(tt.owner)
+			got, err := c.GetInstallationByOwner(context.Background(), tt.owner)
 
 			if tt.wantErr {
 				if err == nil {
@@ -153,12 +155,108 @@ func TestGetInstallationByOwner(t *testing.T) {
 	}
 }
 
+func TestGetInstallationByOwner_Unauthorized(t *testing.T) {
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`), nil
+		},
+	}
+	c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+	_, err := c.GetInstallationByOwner(context.Background(), "myorg")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestGetInstallationByOwner_ClockSkew(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		date     string
+		wantSkew bool
+	}{
+		{
+			name:     "exp too far in the future",
+			body:     `{"message": "'Expiration time' claim ('exp') is too far in the future"}`,
+			date:     time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			wantSkew: true,
+		},
+		{
+			name:     "iat in the future",
+			body:     `{"message": "'Issued at' claim ('iat') must be an Integer representing the time that the assertion was issued"}`,
+			date:     time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+			wantSkew: true,
+		},
+		{
+			name: "other rejection",
+			body: `{"message": "A JSON web token could not be decoded"}`,
+			date: time.Now().UTC().Format(http.TimeFormat),
+		},
+		{
+			name: "no date header",
+			body: `{"message": "'Expiration time' claim ('exp') is too far in the future"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					resp := newResponse(http.StatusUnauthorized, tt.body)
+					if tt.date != "" {
+						resp.Header.Set("Date", tt.date)
+					}
+					return resp, nil
+				},
+			}
+			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+			_, err := c.GetInstallationByOwner(context.Background(), "myorg")
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("err = %v, want ErrUnauthorized", err)
+			}
+
+			var skew *ClockSkewError
+			if got := errors.As(err, &skew); got != tt.wantSkew {
+				t.Fatalf("errors.As(ClockSkewError) = %v, want %v", got, tt.wantSkew)
+			}
+			if !tt.wantSkew {
+				return
+			}
+			want, _ := http.ParseTime(tt.date)
+			if d := time.Until(want) - skew.Offset; d < -5*time.Second || d > 5*time.Second {
+				t.Errorf("Offset = %v, want about %v", skew.Offset, time.Until(want))
+			}
+		})
+	}
+}
+
+func TestGetInstallationByOwner_ContextCanceled(t *testing.T) {
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}
+	c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetInstallationByOwner(ctx, "myorg")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
 func TestGetInstallationAccessToken(t *testing.T) {
 	tests := []struct {
 		name           string
 		installationID int64
 		permissions    map[string]string
 		repos          []string
+		repoIDs        []int64
 		roundTripFunc  func(req *http.Request) (*http.Response, error)
 		wantToken      string
 		wantErr        bool
@@ -204,6 +302,7 @@ func TestGetInstallationAccessToken(t *testing.T) {
 			installationID: 456,
 			permissions:    map[string]string{"issues": "write"},
 			repos:          []string{"myrepo"},
+			repoIDs:        []int64{1296269},
 			roundTripFunc: func(req *http.Request) (*http.Response, error) {
 				return newResponse(http.StatusCreated, `{"token": "ghs_yyy"}`), nil
 			},
@@ -233,6 +332,9 @@ func TestGetInstallationAccessToken(t *testing.T) {
 				if len(payload.Repositories) != 1 || payload.Repositories[0] != "myrepo" {
 					t.Errorf("Repositories = %v, want [myrepo]", payload.Repositories)
 				}
+				if len(payload.RepositoryIDs) != 1 || payload.RepositoryIDs[0] != 1296269 {
+					t.Errorf("RepositoryIDs = %v, want [1296269]", payload.RepositoryIDs)
+				}
 				if payload.Permissions["issues"] != "write" {
 					t.Errorf("Permissions[issues] = %q, want %q", payload.Permissions["issues"], "write")
 				}
@@ -251,7 +353,7 @@ func TestGetInstallationAccessToken(t *testing.T) {
 			}
 			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
 
-			got, err := c.GetInstallationAccessToken(tt.installationID, tt.permissions, tt.repos)
+			got, err := c.GetInstallationAccessToken(context.Background(), tt.installationID, tt.permissions, tt.repos, tt.repoIDs)
 
 			if tt.wantErr {
 				if err == nil {
@@ -272,6 +374,40 @@ func TestGetInstallationAccessToken(t *testing.T) {
 	}
 }
 
+func TestGetInstallationAccessToken_Response(t *testing.T) {
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusCreated, `{
+				"token": "ghs_xxx",
+				"expires_at": "2026-10-18T12:00:00Z",
+				"permissions": {"contents": "read", "metadata": "read"},
+				"repository_selection": "selected",
+				"repositories": [{"id": 1296269, "name": "Hello-World", "full_name": "octocat/Hello-World", "private": false}]
+			}`), nil
+		},
+	}
+	c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+	got, err := c.GetInstallationAccessToken(context.Background(), 123, nil, []string{"Hello-World"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC); !got.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, want)
+	}
+	if len(got.Permissions) != 2 || got.Permissions["contents"] != "read" || got.Permissions["metadata"] != "read" {
+		t.Errorf("Permissions = %v, want map[contents:read metadata:read]", got.Permissions)
+	}
+	if got.RepositorySelection != "selected" {
+		t.Errorf("RepositorySelection = %q, want %q", got.RepositorySelection, "selected")
+	}
+	want := Repository{ID: 1296269, Name: "Hello-World", FullName: "octocat/Hello-World"}
+	if len(got.Repositories) != 1 || got.Repositories[0] != want {
+		t.Errorf("Repositories = %+v, want [%+v]", got.Repositories, want)
+	}
+}
+
 func TestDeleteInstallationAccessToken(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -333,7 +469,7 @@ func TestDeleteInstallationAccessToken(t *testing.T) {
 			}
 			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
 
-			err := c.DeleteInstallationAccessToken()
+			err := c.DeleteInstallationAccessToken(context.Background())
 
 			if tt.wantErr {
 				if err == nil {
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrUnauthorized matches, with errors.Is, the errors for 401 responses,
+// which GitHub returns when it rejects the JWT, e.g. because it was signed by
+// a key that is not registered for the App.
+var ErrUnauthorized = errors.New("401 Unauthorized")
+
+// APIError is the error for a GitHub API response with an unexpected status.
+type APIError struct {
+	StatusCode int
+	// Message and DocumentationURL are from the response body. When the body
+	// is not a GitHub error object, Message is the body itself.
+	Message          string            `json:"message"`
+	DocumentationURL string            `json:"documentation_url"`
+	Errors           []ValidationError `json:"errors"`
+	// RequestID is the X-GitHub-Request-Id header, which GitHub Support
+	// asks for.
+	RequestID string `json:"-"`
+}
+
+// ValidationError is an entry of the errors of a 422 response.
+type ValidationError struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (v ValidationError) String() string {
+	if v.Message != "" {
+		return v.Message
+	}
+	return strings.Trim(fmt.Sprintf("%s %s %s", v.Resource, v.Field, v.Code), " ")
+}
+
+// newAPIError returns the APIError for resp, whose body has been read as body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{}
+	if err := json.Unmarshal(body, e); err != nil {
+		e = &APIError{Message: strings.TrimSpace(string(body))}
+	}
+	e.StatusCode = resp.StatusCode
+	e.RequestID = resp.Header.Get("X-GitHub-Request-Id")
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	for _, v := range e.Errors {
+		msg += "; " + v.String()
+	}
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Is reports whether target is ErrUnauthorized for a 401 response.
+func (e *APIError) Is(target error) bool {
+	return target == ErrUnauthorized && e.StatusCode == http.StatusUnauthorized
+}
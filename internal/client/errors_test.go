@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		requestID  string
+		want       APIError
+		wantMsg    string
+		wantUnauth bool
+	}{
+		{
+			name:      "未インストール",
+			status:    http.StatusNotFound,
+			body:      `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest/apps/apps#get-a-user-installation-for-the-authenticated-app"}`,
+			requestID: "0400:1234:5678",
+			want: APIError{
+				StatusCode:       http.StatusNotFound,
+				Message:          "Not Found",
+				DocumentationURL: "https://docs.github.com/rest/apps/apps#get-a-user-installation-for-the-authenticated-app",
+				RequestID:        "0400:1234:5678",
+			},
+			wantMsg: "failed to get installation: 404 Not Found: Not Found (request ID 0400:1234:5678)",
+		},
+		{
+			name:   "認証エラー",
+			status: http.StatusUnauthorized,
+			body:   `{"message": "Bad credentials"}`,
+			want: APIError{
+				StatusCode: http.StatusUnauthorized,
+				Message:    "Bad credentials",
+			},
+			wantMsg:    "failed to get installation: 401 Unauthorized: Bad credentials",
+			wantUnauth: true,
+		},
+		{
+			name:   "JSON以外の応答",
+			status: http.StatusBadGateway,
+			body:   "<html>bad gateway</html>\n",
+			want: APIError{
+				StatusCode: http.StatusBadGateway,
+				Message:    "<html>bad gateway</html>",
+			},
+			wantMsg: "failed to get installation: 502 Bad Gateway: <html>bad gateway</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					resp := newResponse(tt.status, tt.body)
+					if tt.requestID != "" {
+						resp.Header.Set("X-GitHub-Request-Id", tt.requestID)
+					}
+					return resp, nil
+				},
+			}
+			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+			c.Retry.MaxAttempts = 1
+
+			_, err := c.GetInstallationByOwner(context.Background(), "myorg")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.want.StatusCode || apiErr.Message != tt.want.Message ||
+				apiErr.DocumentationURL != tt.want.DocumentationURL || apiErr.RequestID != tt.want.RequestID {
+				t.Errorf("APIError = %+v, want %+v", *apiErr, tt.want)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMsg)
+			}
+			if got := errors.Is(err, ErrUnauthorized); got != tt.wantUnauth {
+				t.Errorf("errors.Is(err, ErrUnauthorized) = %v, want %v", got, tt.wantUnauth)
+			}
+		})
+	}
+}
+
+func TestAPIError_ValidationErrors(t *testing.T) {
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusUnprocessableEntity, `{
+				"message": "The permissions requested are not granted to this installation.",
+				"errors": [{"resource": "Installation", "field": "permissions", "code": "invalid"}]
+			}`), nil
+		},
+	}
+	c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+	_, err := c.GetInstallationAccessToken(context.Background(), 42, map[string]string{"issues": "write"}, nil, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	want := ValidationError{Resource: "Installation", Field: "permissions", Code: "invalid"}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0] != want {
+		t.Errorf("Errors = %+v, want [%+v]", apiErr.Errors, want)
+	}
+	wantMsg := "failed to get token: 422 Unprocessable Entity: The permissions requested are not granted to this installation.; Installation permissions invalid"
+	if err.Error() != wantMsg {
+		t.Errorf("Error() = %q, want %q", err.Error(), wantMsg)
+	}
+}
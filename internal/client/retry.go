@@ -0,0 +1,152 @@
+package client
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries requests that fail with a network
+// error, a 5xx response, or a rate limit response (429, or 403 with a
+// Retry-After header or no remaining rate limit). Zero fields take their value
+// from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the number of requests made at most, including the
+	// first one. 1 disables retries.
+	MaxAttempts int
+	// Initial and Max bound the backoff between attempts, which doubles
+	// after each attempt. The actual wait is a random duration between half
+	// the bound and the bound.
+	Initial time.Duration
+	Max     time.Duration
+	// MaxWait is the longest wait asked for by the Retry-After or
+	// X-RateLimit-Reset header that is honored. Longer waits fail instead.
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of a Client from New.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Initial:     500 * time.Millisecond,
+	Max:         5 * time.Second,
+	MaxWait:     time.Minute,
+}
+
+// withDefaults returns p with its zero fields set from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.Initial == 0 {
+		p.Initial = d.Initial
+	}
+	if p.Max == 0 {
+		p.Max = d.Max
+	}
+	if p.MaxWait == 0 {
+		p.MaxWait = d.MaxWait
+	}
+	return p
+}
+
+// wait returns how long to wait before the attempt after attempt, which
+// ended with resp or err, and whether to retry at all.
+func (p RetryPolicy) wait(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	if err != nil {
+		return p.backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return p.backoff(attempt), true
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden:
+		d, ok := rateLimitWait(resp)
+		if !ok {
+			if resp.StatusCode == http.StatusForbidden {
+				// A 403 without rate limit headers is a permission error.
+				return 0, false
+			}
+			d = p.backoff(attempt)
+		}
+		if d > p.MaxWait {
+			return 0, false
+		}
+		return d, true
+	default:
+		return 0, false
+	}
+}
+
+// backoff returns the jittered exponential backoff after attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Initial << (attempt - 1)
+	if d > p.Max || d <= 0 {
+		d = p.Max
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// rateLimitWait returns the wait asked for by the Retry-After header of resp,
+// or until X-RateLimit-Reset when no requests remain.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if sec, err := strconv.Atoi(v); err == nil {
+			return time.Duration(sec) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// do sends req, retrying it according to c.Retry.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	policy := c.Retry.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil && req.Context().Err() != nil {
+			return nil, err
+		}
+
+		d, retry := policy.wait(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
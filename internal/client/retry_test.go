@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClient_Retry(t *testing.T) {
+	ok := func() (*http.Response, error) { return newResponse(http.StatusOK, `{"id": 42}`), nil }
+	status := func(code int, header map[string]string) func() (*http.Response, error) {
+		return func() (*http.Response, error) {
+			resp := newResponse(code, `{"message": "error"}`)
+			for k, v := range header {
+				resp.Header.Set(k, v)
+			}
+			return resp, nil
+		}
+	}
+	networkErr := func() (*http.Response, error) { return nil, errors.New("connection reset by peer") }
+
+	tests := []struct {
+		name         string
+		responses    []func() (*http.Response, error)
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "5xx is retried",
+			responses:    []func() (*http.Response, error){status(http.StatusBadGateway, nil), ok},
+			wantRequests: 2,
+		},
+		{
+			name:         "network error is retried",
+			responses:    []func() (*http.Response, error){networkErr, ok},
+			wantRequests: 2,
+		},
+		{
+			name:         "429 honors Retry-After",
+			responses:    []func() (*http.Response, error){status(http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}), ok},
+			wantRequests: 2,
+		},
+		{
+			name: "403 secondary rate limit honors X-RateLimit-Reset",
+			responses: []func() (*http.Response, error){status(http.StatusForbidden, map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Unix(), 10),
+			}), ok},
+			wantRequests: 2,
+		},
+		{
+			name:         "403 without rate limit headers is not retried",
+			responses:    []func() (*http.Response, error){status(http.StatusForbidden, nil), ok},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "404 is not retried",
+			responses:    []func() (*http.Response, error){status(http.StatusNotFound, nil), ok},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "Retry-After beyond MaxWait is not honored",
+			responses:    []func() (*http.Response, error){status(http.StatusTooManyRequests, map[string]string{"Retry-After": "3600"}), ok},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name: "gives up after MaxAttempts",
+			responses: []func() (*http.Response, error){
+				status(http.StatusInternalServerError, nil),
+				status(http.StatusInternalServerError, nil),
+				status(http.StatusInternalServerError, nil),
+				ok,
+			},
+			wantRequests: 3,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			transport := &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					requests++
+					return tt.responses[requests-1]()
+				},
+			}
+			c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+			_, err := c.GetInstallationByOwner(context.Background(), "myorg")
+
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_Retry_ResendsBody(t *testing.T) {
+	var bodies []string
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			b, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+			if len(bodies) == 1 {
+				return newResponse(http.StatusServiceUnavailable, ""), nil
+			}
+			return newResponse(http.StatusCreated, `{"token": "ghs_test"}`), nil
+		},
+	}
+	c := newClientWithMock("https://api.github.com", "test-jwt", transport)
+
+	if _, err := c.GetInstallationAccessToken(context.Background(), 1, map[string]string{"contents": "read"}, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Errorf("bodies = %q, want the same non-empty body twice", bodies)
+	}
+}
@@ -0,0 +1,117 @@
+// Package failover signs with an ordered list of keys, moving on to the next
+// key when one fails in a way the next one may not, such as a Cloud KMS
+// outage in its location or GitHub rejecting the JWT during key rotation.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/yagihash/ghat/v2/internal/client"
+	"github.com/yagihash/ghat/v2/internal/kms"
+)
+
+// Signer is a signing key in a Chain.
+type Signer interface {
+	Sign(ctx context.Context, data []byte) ([]byte, error)
+	Algorithm() string
+	KeyID() string
+}
+
+// Chain is a Signer that tries its signers in order.
+type Chain struct {
+	signers []Signer
+}
+
+// New returns a Chain of signers, which must all use the same algorithm.
+func New(signers ...Signer) (*Chain, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("no signers to fail over between")
+	}
+
+	alg := signers[0].Algorithm()
+	for _, s := range signers[1:] {
+		if s.Algorithm() != alg {
+			return nil, fmt.Errorf("algorithm %q of key %s differs from %q of key %s", s.Algorithm(), s.KeyID(), alg, signers[0].KeyID())
+		}
+	}
+
+	return &Chain{signers: signers}, nil
+}
+
+// Retryable reports whether err, returned by a signer or by GitHub for a JWT
+// it signed, may not occur with the next signer.
+func Retryable(err error) bool {
+	return kms.IsRetryable(err) || errors.Is(err, client.ErrUnauthorized)
+}
+
+// Try calls fn with each signer of s, or with s itself when it is not a
+// *Chain, until fn succeeds or returns an error that is not Retryable. The
+// errors of all attempts are returned when none succeeds.
+func Try(ctx context.Context, s Signer, fn func(Signer) error) error {
+	signers := []Signer{s}
+	if c, ok := s.(*Chain); ok {
+		signers = c.signers
+	}
+
+	var errs []error
+	for _, s := range signers {
+		err := fn(s)
+		if err == nil {
+			return nil
+		}
+		if len(signers) > 1 {
+			err = fmt.Errorf("key %s: %w", s.KeyID(), err)
+		}
+		errs = append(errs, err)
+
+		if !Retryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Sign signs with the first signer that does not fail with a retryable error.
+func (c *Chain) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	var sig []byte
+	err := Try(ctx, c, func(s Signer) error {
+		var err error
+		sig, err = s.Sign(ctx, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// Algorithm returns the algorithm shared by the signers.
+func (c *Chain) Algorithm() string {
+	return c.signers[0].Algorithm()
+}
+
+// KeyID returns the key IDs of the signers in order, comma-separated.
+func (c *Chain) KeyID() string {
+	id := c.signers[0].KeyID()
+	for _, s := range c.signers[1:] {
+		id += "," + s.KeyID()
+	}
+	return id
+}
+
+// Close closes the signers that implement io.Closer.
+func (c *Chain) Close() error {
+	var errs []error
+	for _, s := range c.signers {
+		if closer, ok := s.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
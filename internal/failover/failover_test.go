@@ -0,0 +1,192 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yagihash/ghat/v2/internal/client"
+)
+
+type mockSigner struct {
+	id     string
+	alg    string
+	err    error
+	calls  int
+	closed bool
+}
+
+func (m *mockSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []byte("sig-" + m.id), nil
+}
+
+func (m *mockSigner) Algorithm() string {
+	if m.alg == "" {
+		return "RS256"
+	}
+	return m.alg
+}
+
+func (m *mockSigner) KeyID() string {
+	return m.id
+}
+
+func (m *mockSigner) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("expected error for no signers but got nil")
+	}
+	if _, err := New(&mockSigner{id: "a"}, &mockSigner{id: "b", alg: "PS256"}); err == nil {
+		t.Error("expected error for mixed algorithms but got nil")
+	}
+}
+
+func TestChain_Sign(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "location is down")
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantSig   string
+		wantCalls []int
+		wantErr   bool
+	}{
+		{
+			name:      "first signer succeeds",
+			errs:      []error{nil, nil},
+			wantSig:   "sig-0",
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "fails over on retryable error",
+			errs:      []error{fmt.Errorf("failed to asymmetric sign: %w", unavailable), nil},
+			wantSig:   "sig-1",
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "fails over on disabled key version",
+			errs:      []error{status.Error(codes.FailedPrecondition, "version is disabled"), nil},
+			wantSig:   "sig-1",
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "stops on non-retryable error",
+			errs:      []error{status.Error(codes.PermissionDenied, "denied"), nil},
+			wantCalls: []int{1, 0},
+			wantErr:   true,
+		},
+		{
+			name:      "stops on non-grpc error",
+			errs:      []error{errors.New("boom"), nil},
+			wantCalls: []int{1, 0},
+			wantErr:   true,
+		},
+		{
+			name:      "all signers fail",
+			errs:      []error{unavailable, unavailable},
+			wantCalls: []int{1, 1},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signers []Signer
+			var mocks []*mockSigner
+			for i, err := range tt.errs {
+				m := &mockSigner{id: fmt.Sprint(i), err: err}
+				mocks = append(mocks, m)
+				signers = append(signers, m)
+			}
+			c, err := New(signers...)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			got, err := c.Sign(context.Background(), []byte("data"))
+			for i, m := range mocks {
+				if m.calls != tt.wantCalls[i] {
+					t.Errorf("signer %d called %d times, want %d", i, m.calls, tt.wantCalls[i])
+				}
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.wantSig {
+				t.Errorf("signature = %q, want %q", got, tt.wantSig)
+			}
+		})
+	}
+}
+
+func TestTry_Unauthorized(t *testing.T) {
+	a, b := &mockSigner{id: "a"}, &mockSigner{id: "b"}
+	c, err := New(a, b)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var used []string
+	err = Try(context.Background(), c, func(s Signer) error {
+		used = append(used, s.KeyID())
+		if s.KeyID() == "a" {
+			return fmt.Errorf("failed to get installation: %w", client.ErrUnauthorized)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(used, ",") != "a,b" {
+		t.Errorf("used = %v, want [a b]", used)
+	}
+}
+
+func TestTry_SingleSigner(t *testing.T) {
+	s := &mockSigner{id: "a"}
+	calls := 0
+	err := Try(context.Background(), s, func(Signer) error {
+		calls++
+		return client.ErrUnauthorized
+	})
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestChain_Close(t *testing.T) {
+	a, b := &mockSigner{id: "a"}, &mockSigner{id: "b"}
+	c, err := New(a, b)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !a.closed || !b.closed {
+		t.Errorf("closed = %v, %v, want both true", a.closed, b.closed)
+	}
+}
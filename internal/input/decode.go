@@ -0,0 +1,90 @@
+package input
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types below decode like their underlying types, except that an empty
+// string decodes to the zero value. GitHub Actions passes optional inputs
+// without a default as empty strings, which the built-in envconfig decoders
+// reject.
+
+type Int int
+
+func (i *Int) Decode(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		*i = 0
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*i = Int(n)
+
+	return nil
+}
+
+type Bool bool
+
+func (b *Bool) Decode(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		*b = false
+		return nil
+	}
+
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*b = Bool(v)
+
+	return nil
+}
+
+type Duration time.Duration
+
+func (d *Duration) Decode(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		*d = 0
+		return nil
+	}
+
+	n, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(n)
+
+	return nil
+}
+
+// List is a comma or newline-separated list. Blank entries are dropped.
+type List []string
+
+func (l *List) Decode(value string) error {
+	*l = splitList(value)
+	return nil
+}
+
+func splitList(value string) []string {
+	res := make([]string, 0)
+	normalized := strings.ReplaceAll(value, "\n", ",")
+	for _, v := range strings.Split(normalized, ",") {
+		trimmed := strings.TrimSpace(v)
+		if trimmed != "" {
+			res = append(res, trimmed)
+		}
+	}
+
+	return res
+}
@@ -1,24 +1,136 @@
 package input
 
 import (
+	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
+// Signer backends selectable with the signer input.
+const (
+	SignerKMS        = "kms"
+	SignerPrivateKey = "private_key"
+	SignerAWSKMS     = "aws_kms"
+	SignerVault      = "vault"
+	SignerAzureKV    = "azure_key_vault"
+	SignerPKCS11     = "pkcs11"
+	SignerSSHAgent   = "ssh_agent"
+)
+
+// KeyVersionLatest selects the newest usable KMS CryptoKeyVersion.
+const KeyVersionLatest = "latest"
+
 type Config struct {
-	AppID        string            `envconfig:"APP_ID" required:"true"`
-	Owner        string            `envconfig:"OWNER"`
-	Repositories Repositories      `envconfig:"REPOSITORIES"`
-	Permissions  map[string]string `envconfig:"PERMISSION"`
-	BaseURL      string            `envconfig:"BASE_URL" default:"https://api.github.com"`
-
-	ProjectID  string `envconfig:"KMS_PROJECT_ID" required:"true"`
-	KeyRingID  string `envconfig:"KMS_KEYRING_ID" required:"true"`
-	KeyID      string `envconfig:"KMS_KEY_ID" required:"true"`
+	// One of AppID and ClientID is required to identify the GitHub App.
+	// ClientID, which GitHub recommends, takes precedence as the JWT issuer.
+	AppID        string       `envconfig:"APP_ID"`
+	ClientID     string       `envconfig:"CLIENT_ID"`
+	Owner        string       `envconfig:"OWNER"`
+	Repositories Repositories `envconfig:"REPOSITORIES"`
+	// RepositoryIDs scopes the token by repository ID, which, unlike the
+	// name, survives renames and transfers. It can be combined with
+	// Repositories.
+	RepositoryIDs RepositoryIDs     `envconfig:"REPOSITORY_IDS"`
+	Permissions   map[string]string `envconfig:"PERMISSION"`
+	BaseURL       string            `envconfig:"BASE_URL" default:"https://api.github.com"`
+
+	// HTTPTimeout is the timeout of each GitHub API request. Zero uses the
+	// default of the client package.
+	HTTPTimeout Duration `envconfig:"HTTP_TIMEOUT"`
+
+	// JWTExpiry and JWTIssuedAtSkew override the validity of the App JWT and
+	// the offset of its iat claim, e.g. "-30s". Zero values use the defaults.
+	JWTExpiry       Duration `envconfig:"JWT_EXPIRY"`
+	JWTIssuedAtSkew Duration `envconfig:"JWT_ISSUED_AT_SKEW"`
+
+	// OutputJWT also exposes the App JWT used to issue the token as the
+	// masked jwt output, for App-level API calls.
+	OutputJWT Bool `envconfig:"OUTPUT_JWT"`
+
+	// Signer selects the signing backend. When empty, it is inferred from
+	// which backend-specific inputs are set, falling back to SignerKMS.
+	Signer string `envconfig:"SIGNER"`
+
+	// KMSKey is a full CryptoKey or CryptoKeyVersion resource name. It is an
+	// alternative to the separate KMS_PROJECT_ID, KMS_LOCATION,
+	// KMS_KEYRING_ID, KMS_KEY_ID and KMS_KEY_VERSION inputs. Like
+	// KMSFallbackKeys, a CryptoKey name means its latest version.
+	KMSKey string `envconfig:"KMS_KEY"`
+
+	// KMSFallbackKeys are full resource names of further KMS keys to sign
+	// with, in order, when the KMS key fails with a retryable error or GitHub
+	// rejects its signature. A CryptoKey name means its latest version.
+	KMSFallbackKeys List `envconfig:"KMS_FALLBACK_KEYS"`
+
+	// KMSMaxAttempts and KMSTimeout tune how AsymmetricSign is retried; zero
+	// values use the defaults of the kms package.
+	KMSMaxAttempts Int      `envconfig:"KMS_MAX_ATTEMPTS"`
+	KMSTimeout     Duration `envconfig:"KMS_TIMEOUT"`
+
+	// WorkloadIdentityProvider makes the kms signer authenticate with the
+	// GitHub Actions OIDC token instead of Application Default Credentials,
+	// optionally impersonating ServiceAccount.
+	WorkloadIdentityProvider string `envconfig:"WORKLOAD_IDENTITY_PROVIDER"`
+	ServiceAccount           string `envconfig:"SERVICE_ACCOUNT"`
+
+	// ImpersonateServiceAccount makes the kms signer use short-lived
+	// credentials of this service account, obtained with the ambient
+	// credentials (or the workload identity ones), hopping through
+	// ImpersonateDelegates first.
+	ImpersonateServiceAccount string `envconfig:"IMPERSONATE_SERVICE_ACCOUNT"`
+	ImpersonateDelegates      List   `envconfig:"IMPERSONATE_DELEGATES"`
+
+	ProjectID  string `envconfig:"KMS_PROJECT_ID"`
+	KeyRingID  string `envconfig:"KMS_KEYRING_ID"`
+	KeyID      string `envconfig:"KMS_KEY_ID"`
 	KeyVersion string `envconfig:"KMS_KEY_VERSION" default:"1"`
-	Location   string `envconfig:"KMS_LOCATION" required:"true"`
+	Location   string `envconfig:"KMS_LOCATION"`
+
+	// PrivateKey is a PEM-encoded private key. PrivateKeyPath is a path to
+	// one, where "-" means stdin.
+	PrivateKey           string `envconfig:"PRIVATE_KEY"`
+	PrivateKeyPath       string `envconfig:"PRIVATE_KEY_PATH"`
+	PrivateKeyPassphrase string `envconfig:"PRIVATE_KEY_PASSPHRASE"`
+
+	// AWSKMSKeyID is a key ID, key ARN, alias name, or alias ARN.
+	AWSKMSKeyID  string `envconfig:"AWS_KMS_KEY_ID"`
+	AWSKMSRegion string `envconfig:"AWS_KMS_REGION"`
+
+	// The VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE inputs fall back to the
+	// environment variables of the same names used by the Vault CLI.
+	VaultAddress           string `envconfig:"VAULT_ADDR"`
+	VaultNamespace         string `envconfig:"VAULT_NAMESPACE"`
+	VaultToken             string `envconfig:"VAULT_TOKEN"`
+	VaultRoleID            string `envconfig:"VAULT_ROLE_ID"`
+	VaultSecretID          string `envconfig:"VAULT_SECRET_ID"`
+	VaultAppRoleMount      string `envconfig:"VAULT_APPROLE_MOUNT"`
+	VaultTransitMount      string `envconfig:"VAULT_TRANSIT_MOUNT"`
+	VaultTransitKey        string `envconfig:"VAULT_TRANSIT_KEY"`
+	VaultTransitKeyVersion Int    `envconfig:"VAULT_TRANSIT_KEY_VERSION"`
+
+	// AzureKeyVaultURL is e.g. "https://my-vault.vault.azure.net". An empty
+	// AzureKeyVersion means the current version of the key.
+	AzureKeyVaultURL string `envconfig:"AZURE_KEY_VAULT_URL"`
+	AzureKeyName     string `envconfig:"AZURE_KEY_NAME"`
+	AzureKeyVersion  string `envconfig:"AZURE_KEY_VERSION"`
+
+	// PKCS11Slot is a decimal slot ID. Either it or PKCS11TokenLabel selects
+	// the token.
+	PKCS11Module     string `envconfig:"PKCS11_MODULE"`
+	PKCS11Slot       string `envconfig:"PKCS11_SLOT"`
+	PKCS11TokenLabel string `envconfig:"PKCS11_TOKEN_LABEL"`
+	PKCS11PIN        string `envconfig:"PKCS11_PIN"`
+	PKCS11KeyLabel   string `envconfig:"PKCS11_KEY_LABEL"`
+
+	// SSHAuthSock falls back to the SSH_AUTH_SOCK environment variable.
+	// SSHAgentKeyFingerprint accepts OpenSSH SHA256/MD5 fingerprints and the
+	// fingerprint shown on the GitHub App settings page.
+	SSHAuthSock            string `envconfig:"SSH_AUTH_SOCK"`
+	SSHAgentKeyFingerprint string `envconfig:"SSH_AGENT_KEY_FINGERPRINT"`
 }
 
 func Load() (*Config, error) {
@@ -27,6 +139,10 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	if c.AppID == "" && c.ClientID == "" {
+		return nil, fmt.Errorf("required key INPUT_APP_ID or INPUT_CLIENT_ID missing value")
+	}
+
 	if c.Owner == "" {
 		c.Owner = os.Getenv("GITHUB_REPOSITORY_OWNER")
 	}
@@ -35,6 +151,50 @@ func Load() (*Config, error) {
 		c.KeyVersion = "1"
 	}
 
+	// The action sets every input, so envconfig never falls back to the
+	// unprefixed variables of the Vault CLI on its own.
+	if c.VaultAddress == "" {
+		c.VaultAddress = os.Getenv("VAULT_ADDR")
+	}
+	if c.VaultToken == "" {
+		c.VaultToken = os.Getenv("VAULT_TOKEN")
+	}
+	if c.VaultNamespace == "" {
+		c.VaultNamespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	if c.SSHAuthSock == "" {
+		c.SSHAuthSock = os.Getenv("SSH_AUTH_SOCK")
+	}
+
+	if c.Signer == "" {
+		c.Signer = c.detectSigner()
+	}
+
+	if err := c.validateSigner(); err != nil {
+		return nil, err
+	}
+
+	if len(c.KMSFallbackKeys) > 0 && c.Signer != SignerKMS {
+		return nil, fmt.Errorf("INPUT_KMS_FALLBACK_KEYS requires the %s signer", SignerKMS)
+	}
+
+	if c.HTTPTimeout < 0 {
+		return nil, fmt.Errorf("INPUT_HTTP_TIMEOUT cannot be negative")
+	}
+
+	if c.KMSMaxAttempts < 0 || c.KMSTimeout < 0 {
+		return nil, fmt.Errorf("INPUT_KMS_MAX_ATTEMPTS and INPUT_KMS_TIMEOUT cannot be negative")
+	}
+
+	if c.ServiceAccount != "" && c.WorkloadIdentityProvider == "" {
+		return nil, fmt.Errorf("INPUT_SERVICE_ACCOUNT requires INPUT_WORKLOAD_IDENTITY_PROVIDER")
+	}
+
+	if len(c.ImpersonateDelegates) > 0 && c.ImpersonateServiceAccount == "" {
+		return nil, fmt.Errorf("INPUT_IMPERSONATE_DELEGATES requires INPUT_IMPERSONATE_SERVICE_ACCOUNT")
+	}
+
 	if len(c.Permissions) > 0 {
 		lowered := make(map[string]string, len(c.Permissions))
 		for k, v := range c.Permissions {
@@ -46,6 +206,130 @@ func Load() (*Config, error) {
 	return &c, nil
 }
 
+// Issuer returns the JWT issuer: the Client ID when set, otherwise the App ID.
+func (c *Config) Issuer() string {
+	if c.ClientID != "" {
+		return c.ClientID
+	}
+	return c.AppID
+}
+
+func (c *Config) detectSigner() string {
+	switch {
+	case c.PrivateKey != "" || c.PrivateKeyPath != "":
+		return SignerPrivateKey
+	case c.AWSKMSKeyID != "":
+		return SignerAWSKMS
+	case c.VaultTransitKey != "":
+		return SignerVault
+	case c.AzureKeyVaultURL != "":
+		return SignerAzureKV
+	case c.PKCS11Module != "":
+		return SignerPKCS11
+	case c.SSHAgentKeyFingerprint != "":
+		return SignerSSHAgent
+	default:
+		return SignerKMS
+	}
+}
+
+func (c *Config) validateSigner() error {
+	switch c.Signer {
+	case SignerKMS:
+		if c.KMSKey != "" {
+			if c.ProjectID != "" || c.KeyRingID != "" || c.KeyID != "" || c.Location != "" {
+				return fmt.Errorf("INPUT_KMS_KEY cannot be combined with INPUT_KMS_PROJECT_ID, INPUT_KMS_LOCATION, INPUT_KMS_KEYRING_ID or INPUT_KMS_KEY_ID")
+			}
+			// KeyVersion defaults to "1", so the input is checked instead.
+			if os.Getenv("INPUT_KMS_KEY_VERSION") != "" {
+				return fmt.Errorf("INPUT_KMS_KEY cannot be combined with INPUT_KMS_KEY_VERSION: end the name with /cryptoKeyVersions/N, or omit it to use the latest version")
+			}
+		} else if err := requireInputs(map[string]string{
+			"KMS_PROJECT_ID": c.ProjectID,
+			"KMS_KEYRING_ID": c.KeyRingID,
+			"KMS_KEY_ID":     c.KeyID,
+			"KMS_LOCATION":   c.Location,
+		}); err != nil {
+			return err
+		}
+		if c.KeyVersion != KeyVersionLatest {
+			if _, err := strconv.ParseUint(c.KeyVersion, 10, 64); err != nil {
+				return fmt.Errorf("invalid INPUT_KMS_KEY_VERSION %q: must be a number or %q", c.KeyVersion, KeyVersionLatest)
+			}
+		}
+		return nil
+	case SignerPrivateKey:
+		if c.PrivateKey != "" && c.PrivateKeyPath != "" {
+			return fmt.Errorf("only one of INPUT_PRIVATE_KEY and INPUT_PRIVATE_KEY_PATH can be set")
+		}
+		if c.PrivateKey == "" && c.PrivateKeyPath == "" {
+			return fmt.Errorf("one of INPUT_PRIVATE_KEY and INPUT_PRIVATE_KEY_PATH is required")
+		}
+		return nil
+	case SignerAWSKMS:
+		return requireInputs(map[string]string{
+			"AWS_KMS_KEY_ID": c.AWSKMSKeyID,
+		})
+	case SignerVault:
+		if err := requireInputs(map[string]string{
+			"VAULT_ADDR":        c.VaultAddress,
+			"VAULT_TRANSIT_KEY": c.VaultTransitKey,
+		}); err != nil {
+			return err
+		}
+		if c.VaultToken == "" && (c.VaultRoleID == "" || c.VaultSecretID == "") {
+			return fmt.Errorf("INPUT_VAULT_TOKEN or both INPUT_VAULT_ROLE_ID and INPUT_VAULT_SECRET_ID are required")
+		}
+		return nil
+	case SignerAzureKV:
+		return requireInputs(map[string]string{
+			"AZURE_KEY_VAULT_URL": c.AzureKeyVaultURL,
+			"AZURE_KEY_NAME":      c.AzureKeyName,
+		})
+	case SignerPKCS11:
+		if err := requireInputs(map[string]string{
+			"PKCS11_MODULE":    c.PKCS11Module,
+			"PKCS11_KEY_LABEL": c.PKCS11KeyLabel,
+		}); err != nil {
+			return err
+		}
+		if c.PKCS11Slot == "" && c.PKCS11TokenLabel == "" {
+			return fmt.Errorf("INPUT_PKCS11_SLOT or INPUT_PKCS11_TOKEN_LABEL is required")
+		}
+		if c.PKCS11Slot != "" {
+			if _, err := strconv.ParseUint(c.PKCS11Slot, 10, 0); err != nil {
+				return fmt.Errorf("invalid INPUT_PKCS11_SLOT %q: %w", c.PKCS11Slot, err)
+			}
+		}
+		return nil
+	case SignerSSHAgent:
+		return requireInputs(map[string]string{
+			"SSH_AUTH_SOCK":             c.SSHAuthSock,
+			"SSH_AGENT_KEY_FINGERPRINT": c.SSHAgentKeyFingerprint,
+		})
+	default:
+		return fmt.Errorf("unknown signer: %q", c.Signer)
+	}
+}
+
+// requireInputs reports the first missing input in a stable order, using the
+// same wording as envconfig's required tag.
+func requireInputs(inputs map[string]string) error {
+	keys := make([]string, 0, len(inputs))
+	for k := range inputs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		if inputs[k] == "" {
+			return fmt.Errorf("required key INPUT_%s missing value", k)
+		}
+	}
+
+	return nil
+}
+
 type Repositories []string
 
 func (r *Repositories) Decode(value string) error {
@@ -53,14 +337,27 @@ func (r *Repositories) Decode(value string) error {
 		return nil
 	}
 
-	res := make(Repositories, 0)
-	normalized := strings.ReplaceAll(value, "\n", ",")
-	repos := strings.Split(normalized, ",")
-	for _, repo := range repos {
-		trimmed := strings.TrimSpace(repo)
-		if trimmed != "" {
-			res = append(res, trimmed)
+	*r = splitList(value)
+
+	return nil
+}
+
+// RepositoryIDs is a comma or newline-separated list of repository IDs.
+type RepositoryIDs []int64
+
+func (r *RepositoryIDs) Decode(value string) error {
+	ids := splitList(value)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	res := make(RepositoryIDs, len(ids))
+	for i, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid repository ID %q", id)
 		}
+		res[i] = n
 	}
 
 	*r = res
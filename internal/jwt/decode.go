@@ -0,0 +1,43 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Decode returns the header and claims of token without verifying its
+// signature. Numbers are decoded as json.Number.
+func Decode(token string) (header, claims map[string]any, err error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("jwt: expected 3 dot-separated parts, got %d", len(parts))
+	}
+
+	if header, err = decodePart(parts[0]); err != nil {
+		return nil, nil, fmt.Errorf("jwt: decode header: %w", err)
+	}
+	if claims, err = decodePart(parts[1]); err != nil {
+		return nil, nil, fmt.Errorf("jwt: decode claims: %w", err)
+	}
+
+	return header, claims, nil
+}
+
+func decodePart(part string) (map[string]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,56 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	fixedNow := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	signer := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("sig"), nil
+	}}
+	token, err := Build(context.Background(), signer, "Iv23liABCDEF", fixedNow)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "built token", token: token},
+		{name: "surrounding whitespace", token: " " + token + "\n"},
+		{name: "two parts", token: "a.b", wantErr: true},
+		{name: "invalid base64", token: "!!.e30.sig", wantErr: true},
+		{name: "not json", token: "bm90anNvbg.e30.sig", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, claims, err := Decode(tt.token)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if header["alg"] != "RS256" || header["typ"] != "JWT" {
+				t.Errorf("header = %v, want alg RS256 and typ JWT", header)
+			}
+			if claims["iss"] != "Iv23liABCDEF" {
+				t.Errorf("claims[iss] = %v, want %v", claims["iss"], "Iv23liABCDEF")
+			}
+			if claims["exp"] != json.Number("1705320600") {
+				t.Errorf("claims[exp] = %v, want %v", claims["exp"], "1705320600")
+			}
+		})
+	}
+}
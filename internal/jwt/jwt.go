@@ -15,26 +15,75 @@ const (
 	// Expiry is the duration for which the JWT is valid.
 	// GitHub Apps require JWTs to expire within 10 minutes.
 	Expiry = 600 * time.Second
+	// MaxExpiry is the longest Expiry GitHub accepts.
+	MaxExpiry = 10 * time.Minute
 )
 
+type options struct {
+	expiry       time.Duration
+	issuedAtSkew time.Duration
+}
+
+// Option overrides a default of Build.
+type Option func(*options)
+
+// WithExpiry sets the validity of the JWT, measured from now. It must be
+// positive and at most MaxExpiry.
+func WithExpiry(d time.Duration) Option {
+	return func(o *options) {
+		o.expiry = d
+	}
+}
+
+// WithIssuedAtSkew sets the offset of the iat claim from now. It must not be
+// positive.
+func WithIssuedAtSkew(d time.Duration) Option {
+	return func(o *options) {
+		o.issuedAtSkew = d
+	}
+}
+
 // Signer signs arbitrary byte slices. internal/kms.Signer satisfies this interface.
 type Signer interface {
 	Sign(ctx context.Context, data []byte) ([]byte, error)
+	// Algorithm returns the JWS algorithm of the signatures, used as the
+	// alg header.
+	Algorithm() string
 }
 
 // Build constructs and returns a signed GitHub App JWT.
-// appID is the GitHub App's numeric ID (as a string).
+// issuer is the GitHub App's Client ID, which GitHub recommends, or its
+// numeric App ID (as a string).
 // now is the reference time; callers should pass time.Now().
-func Build(ctx context.Context, signer Signer, appID string, now time.Time) (string, error) {
+func Build(ctx context.Context, signer Signer, issuer string, now time.Time, opts ...Option) (string, error) {
+	o := options{
+		expiry:       Expiry,
+		issuedAtSkew: IssuedAtSkew,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.expiry <= 0 || o.expiry > MaxExpiry {
+		return "", fmt.Errorf("jwt: expiry %v is not between 0 and %v", o.expiry, MaxExpiry)
+	}
+	if o.issuedAtSkew > 0 {
+		return "", fmt.Errorf("jwt: issued at skew %v is positive", o.issuedAtSkew)
+	}
+
+	alg := signer.Algorithm()
+	if alg == "" {
+		return "", fmt.Errorf("jwt: signer has no algorithm")
+	}
+
 	header := map[string]any{
-		"typ": "token",
-		"alg": "RS256",
+		"typ": "JWT",
+		"alg": alg,
 	}
 
 	payload := map[string]any{
-		"iat": now.Add(IssuedAtSkew).Unix(),
-		"exp": now.Add(Expiry).Unix(),
-		"iss": appID,
+		"iat": now.Add(o.issuedAtSkew).Unix(),
+		"exp": now.Add(o.expiry).Unix(),
+		"iss": issuer,
 	}
 
 	headerJSON, err := json.Marshal(header)
@@ -12,12 +12,43 @@ import (
 
 type mockSigner struct {
 	signFn func(ctx context.Context, data []byte) ([]byte, error)
+	alg    string
 }
 
 func (m *mockSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
 	return m.signFn(ctx, data)
 }
 
+func (m *mockSigner) Algorithm() string {
+	if m.alg == "" {
+		return "RS256"
+	}
+	return m.alg
+}
+
+func TestBuild_Algorithm(t *testing.T) {
+	signFn := func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("sig"), nil
+	}
+
+	got, err := Build(context.Background(), &mockSigner{signFn: signFn, alg: "RS512"}, "1", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(got, ".")[0])
+	if err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	var header map[string]any
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+	if header["alg"] != "RS512" {
+		t.Errorf("header[alg] = %q, want %q", header["alg"], "RS512")
+	}
+}
+
 func TestBuild(t *testing.T) {
 	fixedNow := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
 	fakeSig := []byte("fakesignature")
@@ -52,8 +83,8 @@ func TestBuild(t *testing.T) {
 				if err := json.Unmarshal(headerBytes, &header); err != nil {
 					t.Fatalf("failed to unmarshal header: %v", err)
 				}
-				if header["typ"] != "token" {
-					t.Errorf("header[typ] = %q, want %q", header["typ"], "token")
+				if header["typ"] != "JWT" {
+					t.Errorf("header[typ] = %q, want %q", header["typ"], "JWT")
 				}
 				if header["alg"] != "RS256" {
 					t.Errorf("header[alg] = %q, want %q", header["alg"], "RS256")
@@ -176,3 +207,85 @@ func TestBuild(t *testing.T) {
 		})
 	}
 }
+
+func TestBuild_Options(t *testing.T) {
+	fixedNow := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	signer := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("sig"), nil
+	}}
+
+	tests := []struct {
+		name    string
+		issuer  string
+		opts    []Option
+		wantIat int64
+		wantExp int64
+		wantErr bool
+	}{
+		{
+			name:    "client ID issuer",
+			issuer:  "Iv23liABCDEF",
+			wantIat: fixedNow.Unix() - 60,
+			wantExp: fixedNow.Unix() + 600,
+		},
+		{
+			name:    "custom expiry and skew",
+			issuer:  "12345",
+			opts:    []Option{WithExpiry(5 * time.Minute), WithIssuedAtSkew(-30 * time.Second)},
+			wantIat: fixedNow.Unix() - 30,
+			wantExp: fixedNow.Unix() + 300,
+		},
+		{
+			name:    "expiry over 10 minutes",
+			issuer:  "12345",
+			opts:    []Option{WithExpiry(11 * time.Minute)},
+			wantErr: true,
+		},
+		{
+			name:    "zero expiry",
+			issuer:  "12345",
+			opts:    []Option{WithExpiry(0)},
+			wantErr: true,
+		},
+		{
+			name:    "positive skew",
+			issuer:  "12345",
+			opts:    []Option{WithIssuedAtSkew(time.Second)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Build(context.Background(), signer, tt.issuer, fixedNow, tt.opts...)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			payloadBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(got, ".")[1])
+			if err != nil {
+				t.Fatalf("failed to decode payload: %v", err)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+				t.Fatalf("failed to unmarshal payload: %v", err)
+			}
+			if payload["iss"] != tt.issuer {
+				t.Errorf("payload[iss] = %v, want %v", payload["iss"], tt.issuer)
+			}
+			if payload["iat"] != float64(tt.wantIat) {
+				t.Errorf("payload[iat] = %v, want %v", payload["iat"], tt.wantIat)
+			}
+			if payload["exp"] != float64(tt.wantExp) {
+				t.Errorf("payload[exp] = %v, want %v", payload["exp"], tt.wantExp)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package kms_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yagihash/ghat/v2/internal/kms"
+	"github.com/yagihash/ghat/v2/internal/kms/kmstest"
+)
+
+const cryptoKey = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+
+func newServer(t *testing.T) *kmstest.Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := kmstest.NewServer(key)
+	t.Cleanup(srv.Close)
+
+	srv.AddVersion(cryptoKey+"/cryptoKeyVersions/1", kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+	srv.AddVersion(cryptoKey+"/cryptoKeyVersions/2", kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+	srv.AddVersion(cryptoKey+"/cryptoKeyVersions/3", kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_DISABLED)
+	srv.AddVersion(cryptoKey+"/cryptoKeyVersions/4", kmspb.CryptoKeyVersion_RSA_SIGN_PSS_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+
+	return srv
+}
+
+func TestNewSigner_Emulator(t *testing.T) {
+	srv := newServer(t)
+	t.Setenv(kms.EndpointEnv, srv.Addr)
+
+	tests := []struct {
+		name        string
+		version     string
+		wantVersion string
+		wantErr     bool
+	}{
+		{name: "pinned version", version: "1", wantVersion: "1"},
+		{name: "latest version", version: kms.LatestVersion, wantVersion: "2"},
+		{name: "disabled version", version: "3", wantErr: true},
+		{name: "PSS version", version: "4", wantErr: true},
+		{name: "missing version", version: "5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, err := kms.NewSigner(ctx, "p", "global", "r", "k", tt.version)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer s.Close()
+
+			if got := s.Version(); got != tt.wantVersion {
+				t.Errorf("Version() = %v, want %v", got, tt.wantVersion)
+			}
+
+			data := []byte("header.payload")
+			sig, err := s.Sign(ctx, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			digest := sha256.Sum256(data)
+			if err := rsa.VerifyPKCS1v15(&srv.Key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewSigner_EmulatorClientOptions(t *testing.T) {
+	srv := newServer(t)
+	srv.FailSign(status.Error(codes.PermissionDenied, "denied"))
+
+	ctx := context.Background()
+	s, err := kms.NewSignerFromResourceName(ctx, cryptoKey+"/cryptoKeyVersions/1", srv.ClientOptions()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	_, err = s.Sign(ctx, []byte("data"))
+	if st, ok := status.FromError(errors.Unwrap(err)); !ok || st.Code() != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied but got %v", err)
+	}
+
+	if _, err := s.Sign(ctx, []byte("data")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := srv.SignCount(); got != 2 {
+		t.Errorf("SignCount() = %d, want 2", got)
+	}
+}
+
+func TestSigner_Sign_Retry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	exhausted := status.Error(codes.ResourceExhausted, "quota exceeded")
+
+	tests := []struct {
+		name          string
+		errs          []error
+		wantSignCount int
+		wantErr       bool
+	}{
+		{name: "retries unavailable and resource exhausted", errs: []error{unavailable, exhausted}, wantSignCount: 3},
+		{name: "gives up after max attempts", errs: []error{unavailable, unavailable, unavailable}, wantSignCount: 3, wantErr: true},
+		{name: "does not retry permission denied", errs: []error{status.Error(codes.PermissionDenied, "denied")}, wantSignCount: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t)
+			srv.FailSign(tt.errs...)
+
+			ctx := context.Background()
+			s, err := kms.NewSignerFromResourceName(ctx, cryptoKey+"/cryptoKeyVersions/1", srv.ClientOptions()...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer s.Close()
+			s.SetRetryConfig(kms.RetryConfig{
+				MaxAttempts: 3,
+				Initial:     time.Millisecond,
+				Max:         time.Millisecond,
+			})
+
+			_, err = s.Sign(ctx, []byte("data"))
+
+			if got := srv.SignCount(); got != tt.wantSignCount {
+				t.Errorf("SignCount() = %d, want %d", got, tt.wantSignCount)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSigner_Sign_Timeout(t *testing.T) {
+	srv := newServer(t)
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	srv.FailSign(unavailable, unavailable, unavailable, unavailable, unavailable)
+
+	s, err := kms.NewSignerFromResourceName(context.Background(), cryptoKey+"/cryptoKeyVersions/1", srv.ClientOptions()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	s.SetRetryConfig(kms.RetryConfig{
+		Initial: time.Second,
+		Max:     time.Second,
+		Timeout: 50 * time.Millisecond,
+	})
+
+	// Timeout also applies when the caller's deadline is later.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := s.Sign(ctx, []byte("data")); err == nil {
+		t.Error("expected error but got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Sign took %v, want it to stop at the 50ms timeout", elapsed)
+	}
+}
+
+func TestSigner_Sign_RateLimit(t *testing.T) {
+	srv := newServer(t)
+
+	ctx := context.Background()
+	s, err := kms.NewSignerFromResourceName(ctx, cryptoKey+"/cryptoKeyVersions/1", srv.ClientOptions()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	s.SetRetryConfig(kms.RetryConfig{RateLimit: 0.001})
+
+	if _, err := s.Sign(ctx, []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The burst is used up, so the next call waits past its deadline.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Sign(ctx, []byte("data")); err == nil {
+		t.Error("expected error but got nil")
+	}
+	if got := srv.SignCount(); got != 1 {
+		t.Errorf("SignCount() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,58 @@
+package kms
+
+import (
+	"fmt"
+	"hash/crc32"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+func crc32c(b []byte) int64 {
+	return int64(crc32.Checksum(b, crc32cTable))
+}
+
+// IntegrityError is returned when an AsymmetricSign or GetPublicKey response
+// fails the end-to-end integrity checks recommended by Cloud KMS. A checksum
+// mismatch usually means the request or the response was corrupted in transit,
+// so Sign retries it once before returning the error.
+type IntegrityError struct {
+	// Check is the response field that failed verification:
+	// "verified_digest_crc32c", "name", "signature_crc32c" or "pem_crc32c".
+	Check string
+	// Want and Got describe the expected and the actual value.
+	Want string
+	Got  string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("kms response failed integrity check %s: want %s, got %s", e.Check, e.Want, e.Got)
+}
+
+// checksum reports whether e is a checksum mismatch rather than a response
+// for another key.
+func (e *IntegrityError) checksum() bool {
+	return e.Check != "name"
+}
+
+// verifyResponse checks the integrity fields of resp against the request sent
+// for keyPath.
+func verifyResponse(keyPath string, resp *kmspb.AsymmetricSignResponse) error {
+	if !resp.GetVerifiedDigestCrc32C() {
+		return &IntegrityError{Check: "verified_digest_crc32c", Want: "true", Got: "false"}
+	}
+
+	if resp.GetName() != keyPath {
+		return &IntegrityError{Check: "name", Want: keyPath, Got: resp.GetName()}
+	}
+
+	if resp.GetSignatureCrc32C() == nil {
+		return &IntegrityError{Check: "signature_crc32c", Want: "a checksum", Got: "none"}
+	}
+	if got, want := resp.GetSignatureCrc32C().GetValue(), crc32c(resp.GetSignature()); got != want {
+		return &IntegrityError{Check: "signature_crc32c", Want: fmt.Sprint(want), Got: fmt.Sprint(got)}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package kms
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const keyNameFormat = "projects/{project}/locations/{location}/keyRings/{key_ring}/cryptoKeys/{key}[/cryptoKeyVersions/{version}]"
+
+var (
+	// projectIDPattern also accepts project numbers and domain-scoped
+	// project IDs such as "example.com:my-project".
+	projectIDPattern = regexp.MustCompile(`^(?:[a-z][a-z0-9.-]*:)?[a-z0-9][a-z0-9-]*$`)
+	locationPattern  = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	// keyRing and cryptoKey IDs are limited to 63 characters by Cloud KMS.
+	resourceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,63}$`)
+)
+
+// KeyName identifies a CryptoKey, or a CryptoKeyVersion when Version is set.
+type KeyName struct {
+	ProjectID string
+	Location  string
+	KeyRingID string
+	KeyID     string
+	Version   string
+}
+
+// ParseKeyName parses a CryptoKey or CryptoKeyVersion resource name, such as
+// the id attribute of a google_kms_crypto_key Terraform resource. Version is
+// empty when name is a CryptoKey.
+func ParseKeyName(name string) (KeyName, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 8 && len(parts) != 10 {
+		return KeyName{}, fmt.Errorf("invalid kms key name %q: expected %s", name, keyNameFormat)
+	}
+
+	collections := []string{"projects", "locations", "keyRings", "cryptoKeys", "cryptoKeyVersions"}
+	for i := 0; i < len(parts); i += 2 {
+		if want := collections[i/2]; parts[i] != want {
+			return KeyName{}, fmt.Errorf("invalid kms key name %q: segment %d is %q, want %q", name, i+1, parts[i], want)
+		}
+	}
+
+	n := KeyName{
+		ProjectID: parts[1],
+		Location:  parts[3],
+		KeyRingID: parts[5],
+		KeyID:     parts[7],
+	}
+	if len(parts) == 10 {
+		n.Version = parts[9]
+		if n.Version == "" {
+			return KeyName{}, fmt.Errorf("invalid kms key name %q: empty key version", name)
+		}
+	}
+
+	if err := n.validate(); err != nil {
+		return KeyName{}, fmt.Errorf("invalid kms key name %q: %w", name, err)
+	}
+
+	return n, nil
+}
+
+// validate checks every segment of n. An empty Version is allowed.
+func (n KeyName) validate() error {
+	checks := []struct {
+		name    string
+		value   string
+		pattern *regexp.Regexp
+		rule    string
+	}{
+		{"project ID", n.ProjectID, projectIDPattern, "lowercase letters, digits and hyphens"},
+		{"location", n.Location, locationPattern, "lowercase letters, digits and hyphens, e.g. us-central1 or global"},
+		{"key ring ID", n.KeyRingID, resourceIDPattern, "up to 63 letters, digits, underscores and hyphens"},
+		{"key ID", n.KeyID, resourceIDPattern, "up to 63 letters, digits, underscores and hyphens"},
+	}
+	for _, c := range checks {
+		if c.value == "" {
+			return fmt.Errorf("%s is empty", c.name)
+		}
+		if !c.pattern.MatchString(c.value) {
+			return fmt.Errorf("invalid %s %q: must be %s", c.name, c.value, c.rule)
+		}
+	}
+
+	if n.Version != "" && n.Version != LatestVersion {
+		if v, err := strconv.ParseUint(n.Version, 10, 64); err != nil || v == 0 {
+			return fmt.Errorf("invalid key version %q: must be a positive number or %q", n.Version, LatestVersion)
+		}
+	}
+
+	return nil
+}
+
+// CryptoKey returns the resource name of the CryptoKey.
+func (n KeyName) CryptoKey() string {
+	return fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s",
+		n.ProjectID, n.Location, n.KeyRingID, n.KeyID)
+}
+
+// String returns the resource name of the CryptoKeyVersion, or of the
+// CryptoKey when Version is empty.
+func (n KeyName) String() string {
+	if n.Version == "" {
+		return n.CryptoKey()
+	}
+	return n.CryptoKey() + "/cryptoKeyVersions/" + n.Version
+}
@@ -0,0 +1,91 @@
+package kms
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseKeyName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    KeyName
+		wantErr bool
+	}{
+		{
+			name:  "crypto key version",
+			input: "projects/my-project/locations/asia-northeast1/keyRings/github-app/cryptoKeys/signing_key/cryptoKeyVersions/3",
+			want: KeyName{
+				ProjectID: "my-project",
+				Location:  "asia-northeast1",
+				KeyRingID: "github-app",
+				KeyID:     "signing_key",
+				Version:   "3",
+			},
+		},
+		{
+			name:  "crypto key",
+			input: "projects/my-project/locations/global/keyRings/github-app/cryptoKeys/signing-key",
+			want: KeyName{
+				ProjectID: "my-project",
+				Location:  "global",
+				KeyRingID: "github-app",
+				KeyID:     "signing-key",
+			},
+		},
+		{
+			name:  "latest version",
+			input: "projects/my-project/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/latest",
+			want: KeyName{
+				ProjectID: "my-project",
+				Location:  "global",
+				KeyRingID: "r",
+				KeyID:     "k",
+				Version:   "latest",
+			},
+		},
+		{
+			name:  "domain-scoped project",
+			input: "projects/example.com:my-project/locations/global/keyRings/r/cryptoKeys/k",
+			want: KeyName{
+				ProjectID: "example.com:my-project",
+				Location:  "global",
+				KeyRingID: "r",
+				KeyID:     "k",
+			},
+		},
+		{name: "empty", input: "", wantErr: true},
+		{name: "key ring only", input: "projects/p/locations/global/keyRings/r", wantErr: true},
+		{name: "trailing slash", input: "projects/p/locations/global/keyRings/r/cryptoKeys/k/", wantErr: true},
+		{name: "wrong collection", input: "projects/p/locations/global/keyrings/r/cryptoKeys/k", wantErr: true},
+		{name: "empty project", input: "projects//locations/global/keyRings/r/cryptoKeys/k", wantErr: true},
+		{name: "uppercase location", input: "projects/p/locations/US/keyRings/r/cryptoKeys/k", wantErr: true},
+		{name: "invalid key ring", input: "projects/p/locations/global/keyRings/my.ring/cryptoKeys/k", wantErr: true},
+		{name: "empty version", input: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/", wantErr: true},
+		{name: "non-numeric version", input: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/v1", wantErr: true},
+		{name: "zero version", input: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyName(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseKeyName() mismatch (-want +got):\n%s", diff)
+			}
+			if got.String() != tt.input {
+				t.Errorf("String() = %v, want %v", got.String(), tt.input)
+			}
+		})
+	}
+}
@@ -2,56 +2,235 @@ package kms
 
 import (
 	"context"
+	"crypto"
+	"crypto/rsa"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"strconv"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"cloud.google.com/go/kms/apiv1/kmspb"
 	"github.com/googleapis/gax-go/v2"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// LatestVersion can be passed as the version to sign with the newest enabled
+// CryptoKeyVersion that is usable as an RS256 key.
+const LatestVersion = "latest"
+
 // KMSClient defines the interface for KMS operations
 type KMSClient interface {
 	AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error)
+	ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest, opts ...gax.CallOption) ([]*kmspb.CryptoKeyVersion, error)
+	GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error)
 	Close() error
 }
 
+// client adapts *kms.KeyManagementClient to KMSClient by draining the
+// ListCryptoKeyVersions iterator.
+type client struct {
+	*kms.KeyManagementClient
+}
+
+func (c *client) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest, opts ...gax.CallOption) ([]*kmspb.CryptoKeyVersion, error) {
+	var versions []*kmspb.CryptoKeyVersion
+
+	it := c.KeyManagementClient.ListCryptoKeyVersions(ctx, req, opts...)
+	for {
+		v, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+}
+
 type Signer struct {
-	client  KMSClient
-	keyPath string
+	client    KMSClient
+	keyPath   string
+	publicKey *rsa.PublicKey
+	// unverified is why publicKey could not be fetched, in which case the
+	// algorithm is not checked and signatures are not verified.
+	unverified error
+	retry      RetryConfig
+	limiter    *rate.Limiter
 }
 
-// NewKMSClient creates a real KMS client
-func NewKMSClient(ctx context.Context) (KMSClient, error) {
-	client, err := kms.NewKeyManagementClient(ctx)
+// EndpointEnv is the environment variable that points the KMS client at an
+// emulator, e.g. "localhost:9010". The connection is then made without TLS
+// and without credentials.
+const EndpointEnv = "GHAT_KMS_ENDPOINT"
+
+// EmulatorOptions returns the client options to connect to a KMS emulator
+// listening on endpoint in plaintext, without credentials.
+func EmulatorOptions(endpoint string) []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(endpoint),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+}
+
+// NewKMSClient creates a real KMS client. opts are passed to the underlying
+// client, e.g. to override the endpoint or add gRPC dial options. When
+// GHAT_KMS_ENDPOINT is set, EmulatorOptions for it are applied first.
+func NewKMSClient(ctx context.Context, opts ...option.ClientOption) (KMSClient, error) {
+	if endpoint := os.Getenv(EndpointEnv); endpoint != "" {
+		opts = append(EmulatorOptions(endpoint), opts...)
+	}
+
+	c, err := kms.NewKeyManagementClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kms client: %w", err)
 	}
-	return client, nil
+	return &client{KeyManagementClient: c}, nil
 }
 
-// NewSigner creates a new Signer with a KMS client
-func NewSigner(ctx context.Context, projectID, location, keyRingID, keyID, version string) (*Signer, error) {
-	client, err := NewKMSClient(ctx)
+// NewSigner creates a new Signer with a KMS client. version may be
+// LatestVersion, in which case the newest usable CryptoKeyVersion is looked up.
+// opts are passed to NewKMSClient.
+func NewSigner(ctx context.Context, projectID, location, keyRingID, keyID, version string, opts ...option.ClientOption) (*Signer, error) {
+	client, err := NewKMSClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSigner(ctx, client, projectID, location, keyRingID, keyID, version)
 	if err != nil {
+		_ = client.Close()
 		return nil, err
 	}
 
-	return newSigner(client, projectID, location, keyRingID, keyID, version), nil
+	return s, nil
+}
+
+// NewSignerFromResourceName creates a new Signer for a CryptoKeyVersion
+// resource name. A CryptoKey resource name signs with the latest version.
+func NewSignerFromResourceName(ctx context.Context, name string, opts ...option.ClientOption) (*Signer, error) {
+	n, err := ParseKeyName(name)
+	if err != nil {
+		return nil, err
+	}
+	if n.Version == "" {
+		n.Version = LatestVersion
+	}
+
+	return NewSigner(ctx, n.ProjectID, n.Location, n.KeyRingID, n.KeyID, n.Version, opts...)
 }
 
 // newSigner creates a new Signer with the given KMS client (for testing)
-func newSigner(client KMSClient, projectID, location, keyRingID, keyID, version string) *Signer {
-	path := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s/cryptoKeyVersions/%s",
-		projectID, location, keyRingID, keyID, version)
+func newSigner(ctx context.Context, client KMSClient, projectID, location, keyRingID, keyID, version string) (*Signer, error) {
+	name := KeyName{
+		ProjectID: projectID,
+		Location:  location,
+		KeyRingID: keyRingID,
+		KeyID:     keyID,
+		Version:   version,
+	}
+	if version == "" {
+		return nil, errors.New("invalid kms key: key version is empty")
+	}
+	if err := name.validate(); err != nil {
+		return nil, fmt.Errorf("invalid kms key: %w", err)
+	}
+
+	if version == LatestVersion {
+		latest, err := latestVersion(ctx, client, name.CryptoKey())
+		if err != nil {
+			return nil, err
+		}
+		name.Version = latest
+	}
+
+	keyPath := name.String()
+	pub, err := fetchPublicKey(ctx, client, keyPath)
+	// roles/cloudkms.signer, which was enough before the public key was
+	// fetched, lacks cloudkms.cryptoKeyVersions.viewPublicKey.
+	var unverified error
+	if status.Code(err) == codes.PermissionDenied {
+		unverified, err = err, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	return &Signer{
-		client:  client,
-		keyPath: path,
+		client:     client,
+		keyPath:    keyPath,
+		publicKey:  pub,
+		unverified: unverified,
+		retry:      DefaultRetryConfig,
+	}, nil
+}
+
+// rs256Algorithms are the CryptoKeyVersion algorithms whose signatures are
+// valid RS256 signatures.
+var rs256Algorithms = map[kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm]bool{
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256: true,
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_3072_SHA256: true,
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256: true,
+}
+
+// latestVersion returns the ID of the newest ENABLED CryptoKeyVersion of
+// keyName whose algorithm is an RSA PKCS#1 SHA-256 variant.
+func latestVersion(ctx context.Context, client KMSClient, keyName string) (string, error) {
+	versions, err := client.ListCryptoKeyVersions(ctx, &kmspb.ListCryptoKeyVersionsRequest{
+		Parent: keyName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list crypto key versions: %w", err)
+	}
+
+	var (
+		latest   string
+		latestID uint64
+	)
+	for _, v := range versions {
+		if v.GetState() != kmspb.CryptoKeyVersion_ENABLED || !rs256Algorithms[v.GetAlgorithm()] {
+			continue
+		}
+
+		id := path.Base(v.GetName())
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("unexpected crypto key version name %q", v.GetName())
+		}
+		if latest == "" || n > latestID {
+			latest, latestID = id, n
+		}
 	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no enabled RSA PKCS#1 SHA-256 version found among %d versions of %s", len(versions), keyName)
+	}
+
+	return latest, nil
+}
+
+// SetRetryConfig replaces the RetryConfig of s. It must not be called
+// concurrently with Sign.
+func (s *Signer) SetRetryConfig(cfg RetryConfig) {
+	s.retry = cfg.withDefaults()
+	s.limiter = s.retry.limiter()
 }
 
 func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.retry.Timeout)
+	defer cancel()
+
 	digest := sha256.Sum256(data)
 
 	req := &kmspb.AsymmetricSignRequest{
@@ -61,16 +240,75 @@ func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
 				Sha256: digest[:],
 			},
 		},
+		DigestCrc32C: wrapperspb.Int64(crc32c(digest[:])),
+	}
+
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed to wait for the kms rate limiter: %w", err)
+		}
 	}
 
-	result, err := s.client.AsymmetricSign(ctx, req)
+	result, err := s.asymmetricSign(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to asymmetric sign: %w", err)
+		return nil, err
+	}
+
+	// Catch a wrong key or algorithm here rather than as a 401 from GitHub.
+	if s.publicKey != nil {
+		if err := rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], result.Signature); err != nil {
+			return nil, fmt.Errorf("signature by %s does not verify with its public key: %w", s.keyPath, err)
+		}
 	}
 
 	return result.Signature, nil
 }
 
+// asymmetricSign calls AsymmetricSign and verifies the integrity of the
+// response, calling it once more when a checksum does not match.
+func (s *Signer) asymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest) (*kmspb.AsymmetricSignResponse, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := s.client.AsymmetricSign(ctx, req, s.retry.callOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to asymmetric sign: %w", err)
+		}
+
+		err = verifyResponse(s.keyPath, result)
+		var integrityErr *IntegrityError
+		if attempt < 2 && errors.As(err, &integrityErr) && integrityErr.checksum() {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// Version returns the ID of the CryptoKeyVersion used for signing, which is
+// the resolved ID when the signer was created with LatestVersion.
+func (s *Signer) Version() string {
+	return path.Base(s.keyPath)
+}
+
+// Unverified returns the PermissionDenied error of fetching the public key,
+// in which case the algorithm of the key was not checked and signatures are
+// not verified, or nil.
+func (s *Signer) Unverified() error {
+	return s.unverified
+}
+
+// KeyID returns the resource name of the CryptoKeyVersion used for signing.
+func (s *Signer) KeyID() string {
+	return s.keyPath
+}
+
 func (s *Signer) Close() error {
 	return s.client.Close()
 }
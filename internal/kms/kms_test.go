@@ -2,18 +2,72 @@ package kms
 
 import (
 	"context"
+	"crypto"
+	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"cloud.google.com/go/kms/apiv1/kmspb"
 	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // mockKMSClient is a mock implementation of KMSClient for testing
 type mockKMSClient struct {
-	asymmetricSignFunc func(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error)
-	closeFunc          func() error
+	asymmetricSignFunc        func(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error)
+	listCryptoKeyVersionsFunc func(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest, opts ...gax.CallOption) ([]*kmspb.CryptoKeyVersion, error)
+	getPublicKeyFunc          func(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error)
+	closeFunc                 func() error
+}
+
+// testKey is the RSA key behind every mock CryptoKeyVersion.
+var testKey = sync.OnceValue(func() *rsa.PrivateKey {
+	b, err := os.ReadFile(filepath.Join("..", "privatekey", "testdata", "rsa.pem"))
+	if err != nil {
+		panic(err)
+	}
+	block, _ := pem.Decode(b)
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+// publicKeyResponse returns a GetPublicKey response for testKey.
+func publicKeyResponse(name string, alg kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm) *kmspb.PublicKey {
+	der, err := x509.MarshalPKIXPublicKey(&testKey().PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	p := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	return &kmspb.PublicKey{
+		Pem:       p,
+		PemCrc32C: wrapperspb.Int64(crc32c([]byte(p))),
+		Algorithm: alg,
+		Name:      name,
+	}
+}
+
+// testSignature signs data with testKey like Cloud KMS does.
+func testSignature(t *testing.T, data []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(nil, testKey(), crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
 }
 
 func (m *mockKMSClient) AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error) {
@@ -23,6 +77,20 @@ func (m *mockKMSClient) AsymmetricSign(ctx context.Context, req *kmspb.Asymmetri
 	return &kmspb.AsymmetricSignResponse{}, nil
 }
 
+func (m *mockKMSClient) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest, opts ...gax.CallOption) ([]*kmspb.CryptoKeyVersion, error) {
+	if m.listCryptoKeyVersionsFunc != nil {
+		return m.listCryptoKeyVersionsFunc(ctx, req, opts...)
+	}
+	return nil, nil
+}
+
+func (m *mockKMSClient) GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error) {
+	if m.getPublicKeyFunc != nil {
+		return m.getPublicKeyFunc(ctx, req, opts...)
+	}
+	return publicKeyResponse(req.Name, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256), nil
+}
+
 func (m *mockKMSClient) Close() error {
 	if m.closeFunc != nil {
 		return m.closeFunc()
@@ -63,10 +131,9 @@ func TestNewSigner(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockClient := &mockKMSClient{}
-			signer := newSigner(mockClient, tt.projectID, tt.location, tt.keyRingID, tt.keyID, tt.version)
-
-			if signer == nil {
-				t.Fatal("newSigner returned nil")
+			signer, err := newSigner(context.Background(), mockClient, tt.projectID, tt.location, tt.keyRingID, tt.keyID, tt.version)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
 			}
 
 			if signer.keyPath != tt.wantPath {
@@ -76,11 +143,250 @@ func TestNewSigner(t *testing.T) {
 			if signer.client != mockClient {
 				t.Error("client was not set correctly")
 			}
+
+			if got := signer.KeyID(); got != tt.wantPath {
+				t.Errorf("KeyID() = %v, want %v", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestNewSigner_InvalidKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectID string
+		location  string
+		keyRingID string
+		keyID     string
+		version   string
+	}{
+		{name: "empty project", location: "global", keyRingID: "r", keyID: "k", version: "1"},
+		{name: "slash in key ID", projectID: "p", location: "global", keyRingID: "r", keyID: "k/cryptoKeyVersions/2", version: "1"},
+		{name: "empty version", projectID: "p", location: "global", keyRingID: "r", keyID: "k"},
+		{name: "invalid version", projectID: "p", location: "global", keyRingID: "r", keyID: "k", version: "v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSigner(context.Background(), &mockKMSClient{}, tt.projectID, tt.location, tt.keyRingID, tt.keyID, tt.version)
+			if err == nil {
+				t.Error("expected error but got nil")
+			}
 		})
 	}
 }
 
+func TestNewSigner_PublicKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		resp           func(name string) *kmspb.PublicKey
+		err            error
+		wantErr        bool
+		wantUnverified bool
+	}{
+		{
+			name: "RSA PKCS#1 SHA-256",
+			resp: func(name string) *kmspb.PublicKey {
+				return publicKeyResponse(name, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256)
+			},
+		},
+		{
+			name: "PSS is rejected",
+			resp: func(name string) *kmspb.PublicKey {
+				return publicKeyResponse(name, kmspb.CryptoKeyVersion_RSA_SIGN_PSS_2048_SHA256)
+			},
+			wantErr: true,
+		},
+		{
+			name: "SHA-512 is rejected",
+			resp: func(name string) *kmspb.PublicKey {
+				return publicKeyResponse(name, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA512)
+			},
+			wantErr: true,
+		},
+		{
+			name: "EC is rejected",
+			resp: func(name string) *kmspb.PublicKey {
+				return publicKeyResponse(name, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256)
+			},
+			wantErr: true,
+		},
+		{
+			name: "corrupted PEM",
+			resp: func(name string) *kmspb.PublicKey {
+				resp := publicKeyResponse(name, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256)
+				resp.Pem = strings.Replace(resp.Pem, "A", "B", 1)
+				return resp
+			},
+			wantErr: true,
+		},
+		{
+			name: "different key",
+			resp: func(name string) *kmspb.PublicKey {
+				return publicKeyResponse(name+"0", kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "request fails",
+			err:     errors.New("permission denied"),
+			wantErr: true,
+		},
+		{
+			name:           "PermissionDenied skips verification",
+			err:            status.Error(codes.PermissionDenied, "Permission 'cloudkms.cryptoKeyVersions.viewPublicKey' denied"),
+			wantUnverified: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockKMSClient{
+				getPublicKeyFunc: func(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return tt.resp(req.Name), nil
+				},
+			}
+
+			signer, err := newSigner(context.Background(), mockClient, "project", "location", "keyring", "key", "1")
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantUnverified {
+				if signer.Unverified() == nil || signer.publicKey != nil {
+					t.Errorf("Unverified() = %v with publicKey %v, want an error and no public key", signer.Unverified(), signer.publicKey)
+				}
+				return
+			}
+			if signer.Unverified() != nil {
+				t.Errorf("Unverified() = %v, want nil", signer.Unverified())
+			}
+			if signer.publicKey.N.Cmp(testKey().N) != 0 {
+				t.Error("publicKey does not match the test key")
+			}
+		})
+	}
+}
+
+func TestNewSigner_LatestVersion(t *testing.T) {
+	const keyName = "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+
+	version := func(id string, state kmspb.CryptoKeyVersion_CryptoKeyVersionState, alg kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm) *kmspb.CryptoKeyVersion {
+		return &kmspb.CryptoKeyVersion{
+			Name:      keyName + "/cryptoKeyVersions/" + id,
+			State:     state,
+			Algorithm: alg,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		versions    []*kmspb.CryptoKeyVersion
+		listErr     error
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name: "picks the highest enabled version",
+			versions: []*kmspb.CryptoKeyVersion{
+				version("2", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+				version("10", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256),
+				version("9", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+			},
+			wantVersion: "10",
+		},
+		{
+			name: "skips disabled and destroyed versions",
+			versions: []*kmspb.CryptoKeyVersion{
+				version("1", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+				version("2", kmspb.CryptoKeyVersion_DISABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+				version("3", kmspb.CryptoKeyVersion_DESTROYED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+				version("4", kmspb.CryptoKeyVersion_PENDING_GENERATION, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+			},
+			wantVersion: "1",
+		},
+		{
+			name: "skips algorithms other than RSA PKCS#1 SHA-256",
+			versions: []*kmspb.CryptoKeyVersion{
+				version("1", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_3072_SHA256),
+				version("2", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PSS_2048_SHA256),
+				version("3", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA512),
+				version("4", kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256),
+			},
+			wantVersion: "1",
+		},
+		{
+			name: "no usable version",
+			versions: []*kmspb.CryptoKeyVersion{
+				version("1", kmspb.CryptoKeyVersion_DISABLED, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "list fails",
+			listErr: errors.New("permission denied"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockKMSClient{
+				listCryptoKeyVersionsFunc: func(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest, opts ...gax.CallOption) ([]*kmspb.CryptoKeyVersion, error) {
+					if req.Parent != keyName {
+						t.Errorf("Parent = %v, want %v", req.Parent, keyName)
+					}
+					return tt.versions, tt.listErr
+				},
+			}
+
+			signer, err := newSigner(context.Background(), mockClient, "p", "l", "r", "k", LatestVersion)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := signer.Version(); got != tt.wantVersion {
+				t.Errorf("Version() = %v, want %v", got, tt.wantVersion)
+			}
+			if want := keyName + "/cryptoKeyVersions/" + tt.wantVersion; signer.KeyID() != want {
+				t.Errorf("KeyID() = %v, want %v", signer.KeyID(), want)
+			}
+		})
+	}
+}
+
+const testKeyPath = "projects/project/locations/location/keyRings/keyring/cryptoKeys/key/cryptoKeyVersions/1"
+
+// signResponse returns a response that passes the integrity checks.
+func signResponse(name string, signature []byte) *kmspb.AsymmetricSignResponse {
+	return &kmspb.AsymmetricSignResponse{
+		Signature:            signature,
+		SignatureCrc32C:      wrapperspb.Int64(crc32c(signature)),
+		VerifiedDigestCrc32C: true,
+		Name:                 name,
+	}
+}
+
 func TestSigner_Sign(t *testing.T) {
+	sig := testSignature(t, []byte("test data"))
+	emptySig := testSignature(t, []byte(""))
+
 	tests := []struct {
 		name           string
 		data           []byte
@@ -91,16 +397,21 @@ func TestSigner_Sign(t *testing.T) {
 		validateDigest bool
 	}{
 		{
-			name: "successfully signs data",
-			data: []byte("test data"),
-			mockResponse: &kmspb.AsymmetricSignResponse{
-				Signature: []byte("mock-signature"),
-			},
+			name:           "successfully signs data",
+			data:           []byte("test data"),
+			mockResponse:   signResponse(testKeyPath, sig),
 			mockError:      nil,
-			wantSignature:  []byte("mock-signature"),
+			wantSignature:  sig,
 			wantError:      false,
 			validateDigest: true,
 		},
+		{
+			name:          "returns error when signature does not verify",
+			data:          []byte("test data"),
+			mockResponse:  signResponse(testKeyPath, emptySig),
+			wantSignature: nil,
+			wantError:     true,
+		},
 		{
 			name:          "returns error when signing fails",
 			data:          []byte("test data"),
@@ -110,13 +421,11 @@ func TestSigner_Sign(t *testing.T) {
 			wantError:     true,
 		},
 		{
-			name: "handles empty data",
-			data: []byte(""),
-			mockResponse: &kmspb.AsymmetricSignResponse{
-				Signature: []byte("signature-for-empty"),
-			},
+			name:           "handles empty data",
+			data:           []byte(""),
+			mockResponse:   signResponse(testKeyPath, emptySig),
 			mockError:      nil,
-			wantSignature:  []byte("signature-for-empty"),
+			wantSignature:  emptySig,
 			wantError:      false,
 			validateDigest: true,
 		},
@@ -137,8 +446,11 @@ func TestSigner_Sign(t *testing.T) {
 				},
 			}
 
-			signer := newSigner(mockClient, "project", "location", "keyring", "key", "1")
 			ctx := context.Background()
+			signer, err := newSigner(ctx, mockClient, "project", "location", "keyring", "key", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			signature, err := signer.Sign(ctx, tt.data)
 
@@ -167,6 +479,130 @@ func TestSigner_Sign(t *testing.T) {
 	}
 }
 
+func TestSigner_Sign_Unverified(t *testing.T) {
+	otherSig := testSignature(t, []byte("other data"))
+	mockClient := &mockKMSClient{
+		getPublicKeyFunc: func(ctx context.Context, req *kmspb.GetPublicKeyRequest, opts ...gax.CallOption) (*kmspb.PublicKey, error) {
+			return nil, status.Error(codes.PermissionDenied, "Permission 'cloudkms.cryptoKeyVersions.viewPublicKey' denied")
+		},
+		asymmetricSignFunc: func(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error) {
+			return signResponse(testKeyPath, otherSig), nil
+		},
+	}
+
+	signer, err := newSigner(context.Background(), mockClient, "project", "location", "keyring", "key", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the public key, the signature is returned unverified.
+	got, err := signer.Sign(context.Background(), []byte("test data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != string(otherSig) {
+		t.Errorf("signature = %x, want %x", got, otherSig)
+	}
+}
+
+func TestSigner_Sign_Integrity(t *testing.T) {
+	sig := testSignature(t, []byte("test data"))
+
+	corrupt := func(resp *kmspb.AsymmetricSignResponse) { resp.Signature = append([]byte{^sig[0]}, sig[1:]...) }
+
+	tests := []struct {
+		name string
+		// modify alters the response to the call with the same index; the
+		// last one applies to any further calls.
+		modify    []func(resp *kmspb.AsymmetricSignResponse)
+		wantCalls int
+		wantCheck string
+	}{
+		{
+			name:      "valid response",
+			modify:    []func(resp *kmspb.AsymmetricSignResponse){func(resp *kmspb.AsymmetricSignResponse) {}},
+			wantCalls: 1,
+		},
+		{
+			name:      "digest not verified",
+			modify:    []func(resp *kmspb.AsymmetricSignResponse){func(resp *kmspb.AsymmetricSignResponse) { resp.VerifiedDigestCrc32C = false }},
+			wantCalls: 2,
+			wantCheck: "verified_digest_crc32c",
+		},
+		{
+			name: "different key",
+			modify: []func(resp *kmspb.AsymmetricSignResponse){func(resp *kmspb.AsymmetricSignResponse) {
+				resp.Name = "projects/other/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+			}},
+			wantCalls: 1,
+			wantCheck: "name",
+		},
+		{
+			name:      "corrupted signature",
+			modify:    []func(resp *kmspb.AsymmetricSignResponse){corrupt},
+			wantCalls: 2,
+			wantCheck: "signature_crc32c",
+		},
+		{
+			name:      "corrupted signature once",
+			modify:    []func(resp *kmspb.AsymmetricSignResponse){corrupt, func(resp *kmspb.AsymmetricSignResponse) {}},
+			wantCalls: 2,
+		},
+		{
+			name:      "missing signature checksum",
+			modify:    []func(resp *kmspb.AsymmetricSignResponse){func(resp *kmspb.AsymmetricSignResponse) { resp.SignatureCrc32C = nil }},
+			wantCalls: 2,
+			wantCheck: "signature_crc32c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("test data")
+			digest := sha256.Sum256(data)
+
+			calls := 0
+			mockClient := &mockKMSClient{
+				asymmetricSignFunc: func(ctx context.Context, req *kmspb.AsymmetricSignRequest, opts ...gax.CallOption) (*kmspb.AsymmetricSignResponse, error) {
+					if got, want := req.GetDigestCrc32C().GetValue(), crc32c(digest[:]); got != want {
+						t.Errorf("digest_crc32c = %v, want %v", got, want)
+					}
+					resp := signResponse(req.Name, sig)
+					tt.modify[min(calls, len(tt.modify)-1)](resp)
+					calls++
+					return resp, nil
+				},
+			}
+
+			ctx := context.Background()
+			signer, err := newSigner(ctx, mockClient, "project", "location", "keyring", "key", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = signer.Sign(ctx, data)
+
+			if calls != tt.wantCalls {
+				t.Errorf("AsymmetricSign calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantCheck == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var integrityErr *IntegrityError
+			if !errors.As(err, &integrityErr) {
+				t.Fatalf("expected *IntegrityError but got %v", err)
+			}
+			if integrityErr.Check != tt.wantCheck {
+				t.Errorf("Check = %v, want %v", integrityErr.Check, tt.wantCheck)
+			}
+		})
+	}
+}
+
 func TestSigner_Close(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -193,8 +629,11 @@ func TestSigner_Close(t *testing.T) {
 				},
 			}
 
-			signer := newSigner(mockClient, "project", "location", "keyring", "key", "1")
-			err := signer.Close()
+			signer, err := newSigner(context.Background(), mockClient, "project", "location", "keyring", "key", "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = signer.Close()
 
 			if tt.wantError && err == nil {
 				t.Error("expected error but got nil")
@@ -0,0 +1,241 @@
+// Package kmstest provides an in-process Cloud KMS server for tests. It serves
+// the subset of the KeyManagementService gRPC API used by ghat, backed by a
+// real RSA key, so that signers and the ghat binary can be exercised end to
+// end without cloud access.
+package kmstest
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/yagihash/ghat/v2/internal/kms"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+func crc32c(b []byte) int64 {
+	return int64(crc32.Checksum(b, crc32cTable))
+}
+
+// Server is a fake KeyManagementService listening on a local TCP port.
+type Server struct {
+	// Addr is the host:port the server listens on. Set GHAT_KMS_ENDPOINT to
+	// it, or pass ClientOptions to the KMS client.
+	Addr string
+	// Key signs for every CryptoKeyVersion added to the server.
+	Key *rsa.PrivateKey
+
+	srv *grpc.Server
+
+	mu         sync.Mutex
+	versions   map[string]*kmspb.CryptoKeyVersion
+	signCount  int
+	signErrors []error
+	// denyPublicKey makes GetPublicKey fail with PermissionDenied.
+	denyPublicKey bool
+
+	kmspb.UnimplementedKeyManagementServiceServer
+}
+
+// NewServer starts a Server that signs with key. It has no CryptoKeyVersions
+// until AddVersion is called. The caller should call Close when finished.
+func NewServer(key *rsa.PrivateKey) *Server {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("kmstest: failed to listen: %v", err))
+	}
+
+	s := &Server{
+		Addr:     lis.Addr().String(),
+		Key:      key,
+		srv:      grpc.NewServer(),
+		versions: make(map[string]*kmspb.CryptoKeyVersion),
+	}
+	kmspb.RegisterKeyManagementServiceServer(s.srv, s)
+
+	go func() { _ = s.srv.Serve(lis) }()
+
+	return s
+}
+
+// AddVersion registers the CryptoKeyVersion name, e.g.
+// "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1".
+func (s *Server) AddVersion(name string, alg kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm, state kmspb.CryptoKeyVersion_CryptoKeyVersionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.versions[name] = &kmspb.CryptoKeyVersion{
+		Name:            name,
+		State:           state,
+		Algorithm:       alg,
+		ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+	}
+}
+
+// FailSign makes the next len(errs) AsymmetricSign calls fail with errs, in
+// order. Use status.Error to return a specific gRPC code.
+func (s *Server) FailSign(errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signErrors = append(s.signErrors, errs...)
+}
+
+// DenyPublicKey makes GetPublicKey fail with PermissionDenied, as it does for
+// a caller with only roles/cloudkms.signer.
+func (s *Server) DenyPublicKey() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.denyPublicKey = true
+}
+
+// SignCount returns the number of AsymmetricSign calls received so far.
+func (s *Server) SignCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.signCount
+}
+
+// ClientOptions returns the options to connect a KMS client to the server.
+func (s *Server) ClientOptions() []option.ClientOption {
+	return kms.EmulatorOptions(s.Addr)
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.srv.Stop()
+}
+
+func (s *Server) version(name string) (*kmspb.CryptoKeyVersion, error) {
+	v, ok := s.versions[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s not found", name)
+	}
+	if v.State != kmspb.CryptoKeyVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is not enabled, current state is: %s", name, v.State)
+	}
+	return v, nil
+}
+
+// GetPublicKey implements kmspb.KeyManagementServiceServer.
+func (s *Server) GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest) (*kmspb.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.denyPublicKey {
+		return nil, status.Errorf(codes.PermissionDenied, "permission 'cloudkms.cryptoKeyVersions.viewPublicKey' denied on %s", req.GetName())
+	}
+
+	v, err := s.version(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&s.Key.PublicKey)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	p := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	return &kmspb.PublicKey{
+		Pem:             p,
+		PemCrc32C:       wrapperspb.Int64(crc32c([]byte(p))),
+		Algorithm:       v.Algorithm,
+		Name:            v.Name,
+		ProtectionLevel: v.ProtectionLevel,
+	}, nil
+}
+
+// AsymmetricSign implements kmspb.KeyManagementServiceServer.
+func (s *Server) AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest) (*kmspb.AsymmetricSignResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signCount++
+	if len(s.signErrors) > 0 {
+		err := s.signErrors[0]
+		s.signErrors = s.signErrors[1:]
+		return nil, err
+	}
+
+	v, err := s.version(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	digest := req.GetDigest().GetSha256()
+	if len(digest) != sha256.Size {
+		return nil, status.Error(codes.InvalidArgument, "digest must be a SHA-256 digest")
+	}
+	if req.GetDigestCrc32C() != nil && req.GetDigestCrc32C().GetValue() != crc32c(digest) {
+		return nil, status.Error(codes.InvalidArgument, "digest_crc32c does not match digest")
+	}
+
+	var sig []byte
+	switch v.Algorithm {
+	case kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256,
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_3072_SHA256,
+		kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256:
+		sig, err = rsa.SignPKCS1v15(nil, s.Key, crypto.SHA256, digest)
+	case kmspb.CryptoKeyVersion_RSA_SIGN_PSS_2048_SHA256,
+		kmspb.CryptoKeyVersion_RSA_SIGN_PSS_3072_SHA256,
+		kmspb.CryptoKeyVersion_RSA_SIGN_PSS_4096_SHA256:
+		sig, err = rsa.SignPSS(rand.Reader, s.Key, crypto.SHA256, digest, nil)
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "kmstest: algorithm %s is not supported", v.Algorithm)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &kmspb.AsymmetricSignResponse{
+		Signature:            sig,
+		SignatureCrc32C:      wrapperspb.Int64(crc32c(sig)),
+		VerifiedDigestCrc32C: req.GetDigestCrc32C() != nil,
+		Name:                 v.Name,
+		ProtectionLevel:      v.ProtectionLevel,
+	}, nil
+}
+
+// ListCryptoKeyVersions implements kmspb.KeyManagementServiceServer.
+func (s *Server) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest) (*kmspb.ListCryptoKeyVersionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := req.GetParent() + "/cryptoKeyVersions/"
+	var versions []*kmspb.CryptoKeyVersion
+	for name, v := range s.versions {
+		if strings.HasPrefix(name, prefix) {
+			versions = append(versions, v)
+		}
+	}
+	slices.SortFunc(versions, func(a, b *kmspb.CryptoKeyVersion) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return &kmspb.ListCryptoKeyVersionsResponse{
+		CryptoKeyVersions: versions,
+		TotalSize:         int32(len(versions)),
+	}, nil
+}
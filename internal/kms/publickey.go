@@ -0,0 +1,49 @@
+package kms
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// fetchPublicKey retrieves the public key of the CryptoKeyVersion keyPath and
+// rejects keys whose signatures GitHub would not accept as RS256.
+func fetchPublicKey(ctx context.Context, client KMSClient, keyPath string) (*rsa.PublicKey, error) {
+	resp, err := client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: keyPath})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key: %w", err)
+	}
+
+	if resp.GetName() != keyPath {
+		return nil, &IntegrityError{Check: "name", Want: keyPath, Got: resp.GetName()}
+	}
+	if resp.GetPemCrc32C() != nil {
+		if got, want := resp.GetPemCrc32C().GetValue(), crc32c([]byte(resp.GetPem())); got != want {
+			return nil, &IntegrityError{Check: "pem_crc32c", Want: fmt.Sprint(want), Got: fmt.Sprint(got)}
+		}
+	}
+
+	if alg := resp.GetAlgorithm(); !rs256Algorithms[alg] {
+		return nil, fmt.Errorf("kms key %s uses algorithm %s: GitHub requires RS256, i.e. RSA_SIGN_PKCS1_2048_SHA256, RSA_SIGN_PKCS1_3072_SHA256 or RSA_SIGN_PKCS1_4096_SHA256", keyPath, alg)
+	}
+
+	block, _ := pem.Decode([]byte(resp.GetPem()))
+	if block == nil {
+		return nil, errors.New("failed to decode public key: no PEM block found")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	pub, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key must be RSA, got %T", parsed)
+	}
+
+	return pub, nil
+}
@@ -0,0 +1,142 @@
+package kms
+
+import (
+	"time"
+
+	"github.com/googleapis/gax-go/v2"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryableCodes are the status codes of Cloud KMS errors that another
+// CryptoKeyVersion or location may not fail with: outages and throttling of
+// the serving location, and a version that has been disabled or destroyed.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:        true,
+	codes.DeadlineExceeded:   true,
+	codes.ResourceExhausted:  true,
+	codes.Internal:           true,
+	codes.FailedPrecondition: true,
+}
+
+// IsRetryable reports whether err, which may wrap a Cloud KMS error, is worth
+// retrying with a different key.
+func IsRetryable(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && retryableCodes[s.Code()]
+}
+
+// signRetryCodes are the status codes on which AsymmetricSign is retried with
+// the same key.
+var signRetryCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+}
+
+// RetryConfig controls how Sign retries AsymmetricSign on Unavailable,
+// DeadlineExceeded and ResourceExhausted errors. Zero fields take their value
+// from DefaultRetryConfig.
+type RetryConfig struct {
+	// MaxAttempts is the number of AsymmetricSign calls made at most,
+	// including the first one.
+	MaxAttempts int
+	// Initial and Max bound the backoff between attempts, which grows by
+	// Multiplier after each attempt. The actual wait is a random duration up
+	// to the current bound.
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Timeout is the deadline of a Sign call, spanning all of its attempts.
+	// An earlier deadline of the context passed to Sign still applies.
+	Timeout time.Duration
+
+	// RateLimit is the maximum number of AsymmetricSign calls per second
+	// made by the Signer, with bursts of up to Burst calls. Zero means no
+	// limit. It is not shared with other Signers or processes.
+	RateLimit float64
+	Burst     int
+}
+
+// DefaultRetryConfig is the RetryConfig of a new Signer.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 5,
+	Initial:     250 * time.Millisecond,
+	Max:         5 * time.Second,
+	Multiplier:  2,
+	Timeout:     30 * time.Second,
+	Burst:       1,
+}
+
+// withDefaults returns c with its zero fields set from DefaultRetryConfig.
+func (c RetryConfig) withDefaults() RetryConfig {
+	d := DefaultRetryConfig
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = d.MaxAttempts
+	}
+	if c.Initial == 0 {
+		c.Initial = d.Initial
+	}
+	if c.Max == 0 {
+		c.Max = d.Max
+	}
+	if c.Multiplier == 0 {
+		c.Multiplier = d.Multiplier
+	}
+	if c.Timeout == 0 {
+		c.Timeout = d.Timeout
+	}
+	if c.Burst == 0 {
+		c.Burst = d.Burst
+	}
+	return c
+}
+
+// callOptions returns the gax options that apply the retries of c to an
+// AsymmetricSign call. Timeout is applied by Sign instead, since gax ignores
+// it when the context already has a deadline.
+func (c RetryConfig) callOptions() []gax.CallOption {
+	return []gax.CallOption{
+		gax.WithRetry(func() gax.Retryer {
+			return &retryer{
+				backoff: gax.Backoff{
+					Initial:    c.Initial,
+					Max:        c.Max,
+					Multiplier: c.Multiplier,
+				},
+				maxAttempts: c.MaxAttempts,
+			}
+		}),
+	}
+}
+
+// limiter returns the rate limiter for c, or nil when it has no RateLimit.
+func (c RetryConfig) limiter() *rate.Limiter {
+	if c.RateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(c.RateLimit), c.Burst)
+}
+
+// retryer is a gax.Retryer that retries on signRetryCodes with jittered
+// exponential backoff, up to maxAttempts calls in total.
+type retryer struct {
+	backoff     gax.Backoff
+	maxAttempts int
+	attempts    int
+}
+
+func (r *retryer) Retry(err error) (time.Duration, bool) {
+	r.attempts++
+	if r.attempts >= r.maxAttempts {
+		return 0, false
+	}
+
+	s, ok := status.FromError(err)
+	if !ok || !signRetryCodes[s.Code()] {
+		return 0, false
+	}
+
+	return r.backoff.Pause(), true
+}
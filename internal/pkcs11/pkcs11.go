@@ -0,0 +1,19 @@
+// Package pkcs11 signs with an RSA key held in a PKCS#11 token, such as a
+// network HSM or SoftHSM. It requires cgo; binaries built with
+// CGO_ENABLED=0 return an error from NewSigner.
+package pkcs11
+
+// Config identifies the PKCS#11 module, token, and key to sign with.
+type Config struct {
+	// ModulePath is the path to the PKCS#11 shared library,
+	// e.g. "/usr/lib/softhsm/libsofthsm2.so".
+	ModulePath string
+	// SlotID selects the token by slot. Either SlotID or TokenLabel is required.
+	SlotID *uint
+	// TokenLabel selects the token by label.
+	TokenLabel string
+	// PIN is the user PIN of the token.
+	PIN string
+	// KeyLabel is the CKA_LABEL of the RSA private key.
+	KeyLabel string
+}
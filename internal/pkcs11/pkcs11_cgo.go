@@ -0,0 +1,220 @@
+//go:build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+)
+
+// module is the subset of *p11.Ctx used by Signer.
+type module interface {
+	Initialize(opts ...p11.InitializeOption) error
+	Finalize() error
+	Destroy()
+	GetSlotList(tokenPresent bool) ([]uint, error)
+	GetTokenInfo(slotID uint) (p11.TokenInfo, error)
+	OpenSession(slotID uint, flags uint) (p11.SessionHandle, error)
+	CloseSession(sh p11.SessionHandle) error
+	Login(sh p11.SessionHandle, userType uint, pin string) error
+	Logout(sh p11.SessionHandle) error
+	FindObjectsInit(sh p11.SessionHandle, temp []*p11.Attribute) error
+	FindObjects(sh p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error)
+	FindObjectsFinal(sh p11.SessionHandle) error
+	SignInit(sh p11.SessionHandle, m []*p11.Mechanism, o p11.ObjectHandle) error
+	Sign(sh p11.SessionHandle, message []byte) ([]byte, error)
+}
+
+type Signer struct {
+	// mu serializes use of the session, which PKCS#11 does not allow to be
+	// shared between concurrent operations.
+	mu         sync.Mutex
+	module     module
+	session    p11.SessionHandle
+	key        p11.ObjectHandle
+	tokenLabel string
+	keyLabel   string
+}
+
+// NewSigner loads the PKCS#11 module, logs in to the token, and looks up the
+// private key by label.
+func NewSigner(cfg Config) (*Signer, error) {
+	if cfg.ModulePath == "" {
+		return nil, errors.New("pkcs11 module path is required")
+	}
+
+	m := p11.New(cfg.ModulePath)
+	if m == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 module %s", cfg.ModulePath)
+	}
+
+	s, err := newSigner(m, cfg)
+	if err != nil {
+		m.Destroy()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newSigner creates a new Signer with the given module (for testing)
+func newSigner(m module, cfg Config) (*Signer, error) {
+	if cfg.SlotID == nil && cfg.TokenLabel == "" {
+		return nil, errors.New("either a pkcs11 slot ID or token label is required")
+	}
+	if cfg.KeyLabel == "" {
+		return nil, errors.New("pkcs11 key label is required")
+	}
+
+	if err := m.Initialize(); err != nil && !errors.Is(err, p11.Error(p11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		return nil, fmt.Errorf("failed to initialize pkcs11 module: %w", err)
+	}
+
+	s, err := open(m, cfg)
+	if err != nil {
+		_ = m.Finalize()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func open(m module, cfg Config) (*Signer, error) {
+	slot, label, err := findSlot(m, cfg.SlotID, cfg.TokenLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := m.OpenSession(slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pkcs11 session: %w", err)
+	}
+
+	if err := m.Login(session, p11.CKU_USER, cfg.PIN); err != nil && !errors.Is(err, p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN)) {
+		_ = m.CloseSession(session)
+		return nil, fmt.Errorf("failed to log in to pkcs11 token: %w", err)
+	}
+
+	key, err := findKey(m, session, cfg.KeyLabel)
+	if err != nil {
+		_ = m.Logout(session)
+		_ = m.CloseSession(session)
+		return nil, err
+	}
+
+	return &Signer{
+		module:     m,
+		session:    session,
+		key:        key,
+		tokenLabel: label,
+		keyLabel:   cfg.KeyLabel,
+	}, nil
+}
+
+// findSlot returns the slot holding the token, and the token's label.
+func findSlot(m module, slotID *uint, tokenLabel string) (uint, string, error) {
+	slots, err := m.GetSlotList(true)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to list pkcs11 slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		if slotID != nil && slot != *slotID {
+			continue
+		}
+
+		info, err := m.GetTokenInfo(slot)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to get pkcs11 token info of slot %d: %w", slot, err)
+		}
+
+		if tokenLabel != "" && info.Label != tokenLabel {
+			continue
+		}
+
+		return slot, info.Label, nil
+	}
+
+	if slotID != nil {
+		return 0, "", fmt.Errorf("no pkcs11 token with label %q found in slot %d", tokenLabel, *slotID)
+	}
+	return 0, "", fmt.Errorf("no pkcs11 token with label %q found", tokenLabel)
+}
+
+func findKey(m module, session p11.SessionHandle, label string) (p11.ObjectHandle, error) {
+	template := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+	}
+
+	if err := m.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("failed to find pkcs11 key: %w", err)
+	}
+
+	// Ask for two objects so that ambiguous labels can be reported.
+	objects, _, err := m.FindObjects(session, 2)
+	if finalErr := m.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find pkcs11 key: %w", err)
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("no RSA private key with label %q found", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("multiple RSA private keys with label %q found", label)
+	}
+}
+
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mechanism := []*p11.Mechanism{p11.NewMechanism(p11.CKM_SHA256_RSA_PKCS, nil)}
+	if err := s.module.SignInit(s.session, mechanism, s.key); err != nil {
+		return nil, fmt.Errorf("failed to init pkcs11 sign: %w", err)
+	}
+
+	sig, err := s.module.Sign(s.session, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return sig, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// KeyID returns a PKCS#11 URI (RFC 7512) identifying the key.
+func (s *Signer) KeyID() string {
+	return fmt.Sprintf("pkcs11:token=%s;object=%s;type=private",
+		url.PathEscape(s.tokenLabel), url.PathEscape(s.keyLabel))
+}
+
+// Close logs out, closes the session, and unloads the module.
+func (s *Signer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := errors.Join(
+		s.module.Logout(s.session),
+		s.module.CloseSession(s.session),
+		s.module.Finalize(),
+	)
+	s.module.Destroy()
+
+	return err
+}
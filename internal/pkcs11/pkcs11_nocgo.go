@@ -0,0 +1,31 @@
+//go:build !cgo
+
+package pkcs11
+
+import (
+	"context"
+	"errors"
+)
+
+// Signer is not available without cgo.
+type Signer struct{}
+
+func NewSigner(cfg Config) (*Signer, error) {
+	return nil, errors.New("pkcs11 signer is not available: ghat was built without cgo")
+}
+
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	return nil, errors.New("pkcs11 signer is not available: ghat was built without cgo")
+}
+
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+func (s *Signer) KeyID() string {
+	return ""
+}
+
+func (s *Signer) Close() error {
+	return nil
+}
@@ -0,0 +1,293 @@
+//go:build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"os"
+	"strconv"
+	"testing"
+
+	p11 "github.com/miekg/pkcs11"
+)
+
+// fakeModule is an in-memory PKCS#11 module with one RSA key per label.
+type fakeModule struct {
+	tokens    map[uint]string
+	pin       string
+	keys      map[string][]*rsa.PrivateKey
+	loggedIn  bool
+	found     []p11.ObjectHandle
+	objects   map[p11.ObjectHandle]*rsa.PrivateKey
+	signKey   *rsa.PrivateKey
+	finalized bool
+	destroyed bool
+}
+
+func newFakeModule(t *testing.T, labels ...string) *fakeModule {
+	t.Helper()
+	f := &fakeModule{
+		tokens:  map[uint]string{0: "other-token", 3: "ghat"},
+		pin:     "1234",
+		keys:    make(map[string][]*rsa.PrivateKey),
+		objects: make(map[p11.ObjectHandle]*rsa.PrivateKey),
+	}
+	for _, label := range labels {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.keys[label] = append(f.keys[label], key)
+	}
+	return f
+}
+
+func (f *fakeModule) Initialize(opts ...p11.InitializeOption) error { return nil }
+func (f *fakeModule) Finalize() error                               { f.finalized = true; return nil }
+func (f *fakeModule) Destroy()                                      { f.destroyed = true }
+
+func (f *fakeModule) GetSlotList(tokenPresent bool) ([]uint, error) {
+	return []uint{0, 3}, nil
+}
+
+func (f *fakeModule) GetTokenInfo(slotID uint) (p11.TokenInfo, error) {
+	return p11.TokenInfo{Label: f.tokens[slotID]}, nil
+}
+
+func (f *fakeModule) OpenSession(slotID uint, flags uint) (p11.SessionHandle, error) {
+	return p11.SessionHandle(slotID + 100), nil
+}
+
+func (f *fakeModule) CloseSession(sh p11.SessionHandle) error { return nil }
+
+func (f *fakeModule) Login(sh p11.SessionHandle, userType uint, pin string) error {
+	if pin != f.pin {
+		return p11.Error(p11.CKR_PIN_INCORRECT)
+	}
+	f.loggedIn = true
+	return nil
+}
+
+func (f *fakeModule) Logout(sh p11.SessionHandle) error {
+	f.loggedIn = false
+	return nil
+}
+
+func (f *fakeModule) FindObjectsInit(sh p11.SessionHandle, temp []*p11.Attribute) error {
+	f.found = nil
+	for _, attr := range temp {
+		if attr.Type != p11.CKA_LABEL {
+			continue
+		}
+		for _, key := range f.keys[string(attr.Value)] {
+			h := p11.ObjectHandle(len(f.objects) + 1)
+			f.objects[h] = key
+			f.found = append(f.found, h)
+		}
+	}
+	return nil
+}
+
+func (f *fakeModule) FindObjects(sh p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error) {
+	if len(f.found) > max {
+		return f.found[:max], true, nil
+	}
+	return f.found, false, nil
+}
+
+func (f *fakeModule) FindObjectsFinal(sh p11.SessionHandle) error { return nil }
+
+func (f *fakeModule) SignInit(sh p11.SessionHandle, m []*p11.Mechanism, o p11.ObjectHandle) error {
+	if len(m) != 1 || m[0].Mechanism != p11.CKM_SHA256_RSA_PKCS {
+		return p11.Error(p11.CKR_MECHANISM_INVALID)
+	}
+	f.signKey = f.objects[o]
+	return nil
+}
+
+func (f *fakeModule) Sign(sh p11.SessionHandle, message []byte) ([]byte, error) {
+	if !f.loggedIn {
+		return nil, p11.Error(p11.CKR_USER_NOT_LOGGED_IN)
+	}
+	digest := sha256.Sum256(message)
+	return rsa.SignPKCS1v15(nil, f.signKey, crypto.SHA256, digest[:])
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     []string
+		cfg        Config
+		wantKeyID  string
+		wantErr    bool
+		wantClosed bool
+	}{
+		{
+			name:      "by token label",
+			labels:    []string{"github-app"},
+			cfg:       Config{TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"},
+			wantKeyID: "pkcs11:token=ghat;object=github-app;type=private",
+		},
+		{
+			name:      "by slot",
+			labels:    []string{"github-app"},
+			cfg:       Config{SlotID: uintPtr(3), PIN: "1234", KeyLabel: "github-app"},
+			wantKeyID: "pkcs11:token=ghat;object=github-app;type=private",
+		},
+		{
+			name:       "slot and token label mismatch",
+			labels:     []string{"github-app"},
+			cfg:        Config{SlotID: uintPtr(0), TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"},
+			wantErr:    true,
+			wantClosed: true,
+		},
+		{
+			name:       "wrong PIN",
+			labels:     []string{"github-app"},
+			cfg:        Config{TokenLabel: "ghat", PIN: "0000", KeyLabel: "github-app"},
+			wantErr:    true,
+			wantClosed: true,
+		},
+		{
+			name:       "key not found",
+			labels:     []string{"other"},
+			cfg:        Config{TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"},
+			wantErr:    true,
+			wantClosed: true,
+		},
+		{
+			name:       "ambiguous key label",
+			labels:     []string{"github-app", "github-app"},
+			cfg:        Config{TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"},
+			wantErr:    true,
+			wantClosed: true,
+		},
+		{
+			name:    "missing token selector",
+			labels:  []string{"github-app"},
+			cfg:     Config{PIN: "1234", KeyLabel: "github-app"},
+			wantErr: true,
+		},
+		{
+			name:    "missing key label",
+			labels:  []string{"github-app"},
+			cfg:     Config{TokenLabel: "ghat", PIN: "1234"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeModule(t, tt.labels...)
+			s, err := newSigner(m, tt.cfg)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				if m.finalized != tt.wantClosed {
+					t.Errorf("finalized = %v, want %v", m.finalized, tt.wantClosed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.KeyID(); got != tt.wantKeyID {
+				t.Errorf("KeyID() = %q, want %q", got, tt.wantKeyID)
+			}
+		})
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	m := newFakeModule(t, "github-app")
+	s, err := newSigner(m, Config{TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("header.payload")
+	sig, err := s.Sign(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(&m.keys["github-app"][0].PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestSigner_Close(t *testing.T) {
+	m := newFakeModule(t, "github-app")
+	s, err := newSigner(m, Config{TokenLabel: "ghat", PIN: "1234", KeyLabel: "github-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.loggedIn {
+		t.Error("expected to be logged out")
+	}
+	if !m.finalized || !m.destroyed {
+		t.Error("expected module to be finalized and destroyed")
+	}
+}
+
+// TestSigner_SoftHSM runs against a real PKCS#11 module. It is skipped
+// unless GHAT_TEST_PKCS11_MODULE is set. For example:
+//
+//	softhsm2-util --init-token --free --label ghat --pin 1234 --so-pin 0000
+//	softhsm2-util --import key.pk8 --token ghat --label github-app --id 01 --pin 1234
+//	GHAT_TEST_PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so \
+//	GHAT_TEST_PKCS11_TOKEN_LABEL=ghat \
+//	GHAT_TEST_PKCS11_PIN=1234 \
+//	GHAT_TEST_PKCS11_KEY_LABEL=github-app \
+//	go test ./internal/pkcs11/
+func TestSigner_SoftHSM(t *testing.T) {
+	modulePath := os.Getenv("GHAT_TEST_PKCS11_MODULE")
+	if modulePath == "" {
+		t.Skip("GHAT_TEST_PKCS11_MODULE is not set")
+	}
+
+	cfg := Config{
+		ModulePath: modulePath,
+		TokenLabel: os.Getenv("GHAT_TEST_PKCS11_TOKEN_LABEL"),
+		PIN:        os.Getenv("GHAT_TEST_PKCS11_PIN"),
+		KeyLabel:   os.Getenv("GHAT_TEST_PKCS11_KEY_LABEL"),
+	}
+	if slot := os.Getenv("GHAT_TEST_PKCS11_SLOT"); slot != "" {
+		n, err := strconv.ParseUint(slot, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.SlotID = uintPtr(uint(n))
+	}
+
+	s, err := NewSigner(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close: %v", err)
+		}
+	}()
+
+	sig, err := s.Sign(context.Background(), []byte("header.payload"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sig) < 256 {
+		t.Errorf("signature is too short for an RSA-2048 or larger key: %d bytes", len(sig))
+	}
+}
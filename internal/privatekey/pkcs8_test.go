@@ -0,0 +1,81 @@
+package privatekey
+
+import (
+	"encoding/asn1"
+	"strings"
+	"testing"
+)
+
+// encryptedKey returns an EncryptedPrivateKeyInfo with the given PBKDF2
+// parameters, AES-256-CBC, and dummy encrypted data.
+func encryptedKey(t *testing.T, iterations int, prf asn1.ObjectIdentifier) []byte {
+	t.Helper()
+
+	marshal := func(v any) []byte {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	kdf := pbkdf2Params{
+		Salt:           []byte("saltsalt"),
+		IterationCount: iterations,
+		PRF:            algorithmIdentifier{Algorithm: prf, Parameters: asn1.NullRawValue},
+	}
+	params := pbes2Params{
+		KeyDerivationFunc: algorithmIdentifier{
+			Algorithm:  oidPBKDF2,
+			Parameters: asn1.RawValue{FullBytes: marshal(kdf)},
+		},
+		EncryptionScheme: algorithmIdentifier{
+			Algorithm:  oidAES256CBC,
+			Parameters: asn1.RawValue{FullBytes: marshal(make([]byte, 16))},
+		},
+	}
+	return marshal(encryptedPrivateKeyInfo{
+		Algorithm: algorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: marshal(params)},
+		},
+		EncryptedData: make([]byte, 32),
+	})
+}
+
+func TestDecryptPKCS8_RejectsParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		iterations int
+		prf        asn1.ObjectIdentifier
+		wantErr    string
+	}{
+		{
+			name:       "excessive iteration count",
+			iterations: 1 << 30,
+			prf:        oidHMACWithSHA256,
+			wantErr:    "iteration count",
+		},
+		{
+			name:       "zero iteration count",
+			iterations: 0,
+			prf:        oidHMACWithSHA256,
+			wantErr:    "iteration count",
+		},
+		{
+			name:       "unknown PRF",
+			iterations: 2048,
+			prf:        asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 5},
+			wantErr:    "unsupported PBKDF2 PRF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decryptPKCS8(encryptedKey(t, tt.iterations, tt.prf), []byte(testPassphrase))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
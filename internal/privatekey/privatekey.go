@@ -0,0 +1,136 @@
+package privatekey
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// minKeyBits is the smallest RSA modulus GitHub accepts for App private keys.
+const minKeyBits = 2048
+
+// Signer signs data with an RSA private key held in memory.
+type Signer struct {
+	key *rsa.PrivateKey
+}
+
+// NewSigner parses a PEM-encoded RSA private key and returns a Signer.
+// PKCS#1 ("RSA PRIVATE KEY"), PKCS#8 ("PRIVATE KEY") and encrypted PKCS#8
+// ("ENCRYPTED PRIVATE KEY") blocks are supported. passphrase is only used for
+// encrypted keys.
+func NewSigner(pemBytes, passphrase []byte) (*Signer, error) {
+	key, err := parse(pemBytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{key: key}, nil
+}
+
+// NewSignerFromFile reads a PEM-encoded RSA private key from path.
+func NewSignerFromFile(path string, passphrase []byte) (*Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return NewSigner(b, passphrase)
+}
+
+// NewSignerFromReader reads a PEM-encoded RSA private key from r.
+func NewSignerFromReader(r io.Reader, passphrase []byte) (*Signer, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return NewSigner(b, passphrase)
+}
+
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	sig, err := rsa.SignPKCS1v15(nil, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return sig, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// KeyID returns the SHA-256 fingerprint of the public key, in the same format
+// GitHub shows on the App settings page.
+func (s *Signer) KeyID() string {
+	der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Close is a no-op. It exists so that Signer can be used interchangeably with
+// signers that hold remote connections.
+func (s *Signer) Close() error {
+	return nil
+}
+
+func parse(pemBytes, passphrase []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode private key: no PEM block found")
+	}
+
+	if _, ok := block.Headers["Proc-Type"]; ok {
+		return nil, errors.New("legacy PEM encryption is not supported: convert the key with `openssl pkcs8 -topk8`")
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, errors.New("private key is encrypted but no passphrase was given")
+		}
+		der, derr := decryptPKCS8(block.Bytes, passphrase)
+		if derr != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", derr)
+		}
+		parsed, err = x509.ParsePKCS8PrivateKey(der)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key must be RSA, got %T", parsed)
+	}
+
+	if bits := key.N.BitLen(); bits < minKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits, got %d", minKeyBits, bits)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,144 @@
+// Package sshagent signs GitHub App JWTs with an RSA key loaded into an
+// ssh-agent, such as a YubiKey-backed key or one forwarded over SSH.
+package sshagent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Signer signs data with an RSA key held by an ssh-agent. The private key never
+// leaves the agent.
+type Signer struct {
+	agent       agent.ExtendedAgent
+	conn        io.Closer
+	key         ssh.PublicKey
+	fingerprint string
+}
+
+// NewSigner connects to the ssh-agent listening on socket, usually the value
+// of SSH_AUTH_SOCK, and selects the RSA key matching fingerprint.
+func NewSigner(socket, fingerprint string) (*Signer, error) {
+	if socket == "" {
+		return nil, errors.New("ssh-agent socket is not set: is SSH_AUTH_SOCK exported?")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+
+	s, err := newSigner(agent.NewClient(conn), fingerprint)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	s.conn = conn
+
+	return s, nil
+}
+
+// newSigner creates a new Signer with the given agent (for testing)
+func newSigner(a agent.ExtendedAgent, fingerprint string) (*Signer, error) {
+	if fingerprint == "" {
+		return nil, errors.New("ssh-agent key fingerprint is required")
+	}
+
+	keys, err := a.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if !matchFingerprint(k, fingerprint) {
+			continue
+		}
+
+		if k.Type() != ssh.KeyAlgoRSA {
+			return nil, fmt.Errorf("ssh-agent key %s is %s, want %s", fingerprint, k.Type(), ssh.KeyAlgoRSA)
+		}
+
+		return &Signer{
+			agent:       a,
+			key:         k,
+			fingerprint: ssh.FingerprintSHA256(k),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no ssh-agent key with fingerprint %s found among %d keys", fingerprint, len(keys))
+}
+
+// matchFingerprint reports whether fingerprint identifies key. Both the
+// OpenSSH formats ("SHA256:..." and legacy MD5 "aa:bb:...") and the format
+// shown on the GitHub App settings page, which hashes the PKIX public key,
+// are accepted.
+func matchFingerprint(key ssh.PublicKey, fingerprint string) bool {
+	if fingerprint == ssh.FingerprintSHA256(key) {
+		return true
+	}
+
+	if strings.TrimPrefix(strings.ToLower(fingerprint), "md5:") == ssh.FingerprintLegacyMD5(key) {
+		return true
+	}
+
+	// Keys listed by the agent are *agent.Key, which does not expose the
+	// crypto.PublicKey, so parse the wire format again.
+	parsed, err := ssh.ParsePublicKey(key.Marshal())
+	if err != nil {
+		return false
+	}
+	cpk, ok := parsed.(ssh.CryptoPublicKey)
+	if !ok {
+		return false
+	}
+	der, err := x509.MarshalPKIXPublicKey(cpk.CryptoPublicKey())
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(der)
+
+	return fingerprint == "SHA256:"+base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign asks the agent for an rsa-sha2-256 signature, which is an
+// RSASSA-PKCS1-v1_5 SHA-256 signature and thus byte-compatible with RS256.
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	sig, err := s.agent.SignWithFlags(s.key, data, agent.SignatureFlagRsaSha256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	if sig.Format != ssh.KeyAlgoRSASHA256 {
+		return nil, fmt.Errorf("ssh-agent returned a %s signature, want %s", sig.Format, ssh.KeyAlgoRSASHA256)
+	}
+
+	return sig.Blob, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// KeyID returns the OpenSSH SHA-256 fingerprint of the key.
+func (s *Signer) KeyID() string {
+	return s.fingerprint
+}
+
+// Close closes the connection to the agent.
+func (s *Signer) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,196 @@
+package sshagent
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+// newTestAgent returns an in-process agent holding an RSA and an Ed25519 key.
+func newTestAgent(t *testing.T) (agent.ExtendedAgent, testKeys) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring := agent.NewKeyring()
+	for _, k := range []any{edKey, rsaKey} {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return keyring.(agent.ExtendedAgent), testKeys{rsa: rsaKey, ed25519: edKey}
+}
+
+func sshPublicKey(t *testing.T, key crypto.PublicKey) ssh.PublicKey {
+	t.Helper()
+	pub, err := ssh.NewPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func githubFingerprint(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestNewSigner_Fingerprint(t *testing.T) {
+	a, keys := newTestAgent(t)
+	rsaPub := sshPublicKey(t, &keys.rsa.PublicKey)
+	edPub := sshPublicKey(t, keys.ed25519.Public())
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		wantErr     bool
+	}{
+		{name: "OpenSSH SHA256", fingerprint: ssh.FingerprintSHA256(rsaPub)},
+		{name: "OpenSSH MD5", fingerprint: "MD5:" + ssh.FingerprintLegacyMD5(rsaPub)},
+		{name: "legacy MD5 without prefix", fingerprint: ssh.FingerprintLegacyMD5(rsaPub)},
+		{name: "GitHub App settings page", fingerprint: githubFingerprint(t, &keys.rsa.PublicKey)},
+		{name: "non-RSA key", fingerprint: ssh.FingerprintSHA256(edPub), wantErr: true},
+		{name: "unknown key", fingerprint: "SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", wantErr: true},
+		{name: "empty", fingerprint: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSigner(a, tt.fingerprint)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := s.KeyID(), ssh.FingerprintSHA256(rsaPub); got != want {
+				t.Errorf("KeyID() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	a, keys := newTestAgent(t)
+	s, err := newSigner(a, ssh.FingerprintSHA256(sshPublicKey(t, &keys.rsa.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("header.payload")
+	sig, err := s.Sign(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(&keys.rsa.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature is not RS256-compatible: %v", err)
+	}
+}
+
+// sha1Agent simulates an old agent that ignores the rsa-sha2-256 flag.
+type sha1Agent struct {
+	agent.ExtendedAgent
+}
+
+func (a sha1Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	return a.ExtendedAgent.SignWithFlags(key, data, 0)
+}
+
+func TestSigner_Sign_RejectsSHA1(t *testing.T) {
+	a, keys := newTestAgent(t)
+	s, err := newSigner(sha1Agent{a}, ssh.FingerprintSHA256(sshPublicKey(t, &keys.rsa.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Sign(context.Background(), []byte("data")); err == nil {
+		t.Error("expected error for ssh-rsa signature but got nil")
+	}
+}
+
+func TestNewSigner_Socket(t *testing.T) {
+	a, keys := newTestAgent(t)
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(a, conn)
+			}()
+		}
+	}()
+
+	s, err := NewSigner(socket, githubFingerprint(t, &keys.rsa.PublicKey))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close: %v", err)
+		}
+	}()
+
+	if _, err := s.Sign(context.Background(), []byte("data")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewSigner_NoSocket(t *testing.T) {
+	if _, err := NewSigner("", "SHA256:xxx"); err == nil {
+		t.Error("expected error but got nil")
+	}
+	if _, err := NewSigner(filepath.Join(t.TempDir(), "missing.sock"), "SHA256:xxx"); err == nil {
+		t.Error("expected error but got nil")
+	}
+}
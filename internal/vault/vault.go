@@ -0,0 +1,314 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTransitMount = "transit"
+	defaultAppRoleMount = "approle"
+)
+
+// Config configures a Transit signer.
+type Config struct {
+	// Address is the Vault server address, e.g. "https://vault.example.com:8200".
+	Address string
+	// Namespace is the Vault Enterprise namespace. Optional.
+	Namespace string
+
+	// Token is used to authenticate to Vault. When empty, RoleID and
+	// SecretID are used to log in with the AppRole auth method.
+	Token        string
+	RoleID       string
+	SecretID     string
+	AppRoleMount string
+
+	// TransitMount is the mount path of the Transit secrets engine.
+	// Defaults to "transit".
+	TransitMount string
+	// KeyName is the name of the Transit key.
+	KeyName string
+	// KeyVersion pins the key version to sign with. Zero means the latest.
+	KeyVersion int
+}
+
+// supportedKeyTypes lists the Transit key types that can produce RS256
+// signatures.
+var supportedKeyTypes = []string{"rsa-2048", "rsa-3072", "rsa-4096"}
+
+type Signer struct {
+	address    string
+	namespace  string
+	mount      string
+	keyName    string
+	keyVersion int
+	token      string
+	// revoke is true when the token was obtained by Signer itself and must
+	// be revoked on Close.
+	revoke     bool
+	HTTPClient *http.Client
+}
+
+type signRequest struct {
+	Input              string `json:"input"`
+	HashAlgorithm      string `json:"hash_algorithm"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	KeyVersion         int    `json:"key_version,omitempty"`
+}
+
+type signResponse struct {
+	Data struct {
+		Signature string `json:"signature"`
+	} `json:"data"`
+}
+
+type keyResponse struct {
+	Data struct {
+		Type string `json:"type"`
+	} `json:"data"`
+}
+
+type appRoleLoginRequest struct {
+	RoleID   string `json:"role_id"`
+	SecretID string `json:"secret_id"`
+}
+
+type appRoleLoginResponse struct {
+	Auth struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+}
+
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// NewSigner creates a Signer for the given Transit key. When cfg.Token is
+// empty, it logs in with AppRole first. The key is read to reject types that
+// cannot produce RS256 signatures, which needs the read capability on
+// <mount>/keys/<name>.
+func NewSigner(ctx context.Context, cfg Config) (*Signer, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("vault address is required")
+	}
+	if cfg.KeyName == "" {
+		return nil, errors.New("vault transit key name is required")
+	}
+
+	s := &Signer{
+		address:    strings.TrimSuffix(cfg.Address, "/"),
+		namespace:  cfg.Namespace,
+		mount:      strings.Trim(cfg.TransitMount, "/"),
+		keyName:    cfg.KeyName,
+		keyVersion: cfg.KeyVersion,
+		token:      cfg.Token,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	if s.mount == "" {
+		s.mount = defaultTransitMount
+	}
+
+	if s.token == "" {
+		if cfg.RoleID == "" || cfg.SecretID == "" {
+			return nil, errors.New("either a vault token or an AppRole role ID and secret ID is required")
+		}
+
+		mount := strings.Trim(cfg.AppRoleMount, "/")
+		if mount == "" {
+			mount = defaultAppRoleMount
+		}
+
+		token, err := s.loginAppRole(ctx, mount, cfg.RoleID, cfg.SecretID)
+		if err != nil {
+			return nil, err
+		}
+		s.token = token
+		s.revoke = true
+	}
+
+	if err := s.checkKeyType(ctx); err != nil {
+		if s.revoke {
+			_ = s.Close()
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// checkKeyType returns an error unless the Transit key is an RSA key.
+func (s *Signer) checkKeyType(ctx context.Context) error {
+	path := fmt.Sprintf("%s/keys/%s", s.mount, url.PathEscape(s.keyName))
+	req, err := s.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	var keyResp keyResponse
+	if err := s.do(req, &keyResp); err != nil {
+		return fmt.Errorf("failed to read key %s: %w", s.KeyID(), err)
+	}
+
+	if !slices.Contains(supportedKeyTypes, keyResp.Data.Type) {
+		return fmt.Errorf("key %s has type %q, want one of %s", s.KeyID(), keyResp.Data.Type, strings.Join(supportedKeyTypes, ", "))
+	}
+
+	return nil
+}
+
+func (s *Signer) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	u := fmt.Sprintf("%s/v1/%s", s.address, path)
+
+	var bodyReader io.Reader
+	if body != nil {
+		jsonBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewBuffer(jsonBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.token != "" {
+		req.Header.Set("X-Vault-Token", s.token)
+	}
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+// do sends req and decodes a successful JSON response into out, which may be nil.
+func (s *Signer) do(req *http.Request, out any) error {
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		var errResp errorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.Join(errResp.Errors, "; "))
+		}
+		return fmt.Errorf("%s, body: %s", resp.Status, string(body))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *Signer) loginAppRole(ctx context.Context, mount, roleID, secretID string) (string, error) {
+	path := fmt.Sprintf("auth/%s/login", mount)
+	req, err := s.newRequest(ctx, http.MethodPost, path, appRoleLoginRequest{
+		RoleID:   roleID,
+		SecretID: secretID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var loginResp appRoleLoginResponse
+	if err := s.do(req, &loginResp); err != nil {
+		return "", fmt.Errorf("failed to log in with approle: %w", err)
+	}
+
+	if loginResp.Auth.ClientToken == "" {
+		return "", errors.New("failed to log in with approle: no client token in response")
+	}
+
+	return loginResp.Auth.ClientToken, nil
+}
+
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	path := fmt.Sprintf("%s/sign/%s", s.mount, url.PathEscape(s.keyName))
+	req, err := s.newRequest(ctx, http.MethodPost, path, signRequest{
+		Input:              base64.StdEncoding.EncodeToString(data),
+		HashAlgorithm:      "sha2-256",
+		SignatureAlgorithm: "pkcs1v15",
+		KeyVersion:         s.keyVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var signResp signResponse
+	if err := s.do(req, &signResp); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return decodeSignature(signResp.Data.Signature)
+}
+
+// decodeSignature decodes a Transit signature of the form "vault:v<N>:<base64>".
+func decodeSignature(sig string) ([]byte, error) {
+	parts := strings.SplitN(sig, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return nil, fmt.Errorf("unexpected signature format: %q", sig)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	return b, nil
+}
+
+// Algorithm returns the JWS algorithm of the signatures produced by Sign.
+func (s *Signer) Algorithm() string {
+	return "RS256"
+}
+
+// KeyID returns the Transit key path, including the pinned version if any.
+func (s *Signer) KeyID() string {
+	id := fmt.Sprintf("%s/keys/%s", s.mount, s.keyName)
+	if s.keyVersion > 0 {
+		id = fmt.Sprintf("%s/versions/%d", id, s.keyVersion)
+	}
+	return id
+}
+
+// Close revokes the token if it was obtained with AppRole. Tokens passed in
+// by the caller are left untouched.
+func (s *Signer) Close() error {
+	if !s.revoke {
+		return nil
+	}
+
+	req, err := s.newRequest(context.Background(), http.MethodPost, "auth/token/revoke-self", nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.do(req, nil); err != nil {
+		return fmt.Errorf("failed to revoke vault token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeTransit is an httptest stand-in for the Vault Transit and AppRole APIs.
+type fakeTransit struct {
+	t        *testing.T
+	key      *rsa.PrivateKey
+	keyType  string
+	token    string
+	revoked  bool
+	lastSign signRequest
+}
+
+func newFakeTransit(t *testing.T) *fakeTransit {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeTransit{t: t, key: key, keyType: "rsa-2048", token: "s.static"}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = io.WriteString(w, body)
+}
+
+func (f *fakeTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/auth/approle/login":
+		var req appRoleLoginRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.RoleID != "role" || req.SecretID != "secret" {
+			writeJSON(w, http.StatusBadRequest, `{"errors":["invalid role or secret ID"]}`)
+			return
+		}
+		f.token = "s.approle"
+		writeJSON(w, http.StatusOK, `{"auth":{"client_token":"s.approle"}}`)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/auth/token/revoke-self":
+		if r.Header.Get("X-Vault-Token") != f.token {
+			writeJSON(w, http.StatusForbidden, `{"errors":["permission denied"]}`)
+			return
+		}
+		f.revoked = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/keys/"):
+		if r.Header.Get("X-Vault-Token") != f.token {
+			writeJSON(w, http.StatusForbidden, `{"errors":["permission denied"]}`)
+			return
+		}
+		if _, name, _ := strings.Cut(r.URL.Path, "/keys/"); name != "github-app" {
+			writeJSON(w, http.StatusNotFound, `{"errors":[]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"data":{"name":"github-app","type":"`+f.keyType+`"}}`)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/transit/sign/"):
+		if r.Header.Get("X-Vault-Token") != f.token {
+			writeJSON(w, http.StatusForbidden, `{"errors":["permission denied"]}`)
+			return
+		}
+		if strings.TrimPrefix(r.URL.Path, "/v1/transit/sign/") != "github-app" {
+			writeJSON(w, http.StatusBadRequest, `{"errors":["signing key not found"]}`)
+			return
+		}
+		var req signRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, `{"errors":["bad body"]}`)
+			return
+		}
+		f.lastSign = req
+		input, err := base64.StdEncoding.DecodeString(req.Input)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, `{"errors":["unable to decode input as base64"]}`)
+			return
+		}
+		digest := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(nil, f.key, crypto.SHA256, digest[:])
+		if err != nil {
+			f.t.Error(err)
+			writeJSON(w, http.StatusInternalServerError, `{"errors":["failed to sign"]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"data":{"key_version":1,"signature":"vault:v1:`+base64.StdEncoding.EncodeToString(sig)+`"}}`)
+	default:
+		http.Error(w, "unexpected path: "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{
+			name: "token auth",
+			cfg:  Config{Token: "s.static", KeyName: "github-app"},
+		},
+		{
+			name: "approle auth",
+			cfg:  Config{RoleID: "role", SecretID: "secret", KeyName: "github-app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTransit(t)
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			tt.cfg.Address = srv.URL
+			s, err := NewSigner(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data := []byte("header.payload")
+			sig, err := s.Sign(context.Background(), data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			digest := sha256.Sum256(data)
+			if err := rsa.VerifyPKCS1v15(&fake.key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+			if fake.lastSign.HashAlgorithm != "sha2-256" {
+				t.Errorf("hash_algorithm = %q, want %q", fake.lastSign.HashAlgorithm, "sha2-256")
+			}
+			if fake.lastSign.SignatureAlgorithm != "pkcs1v15" {
+				t.Errorf("signature_algorithm = %q, want %q", fake.lastSign.SignatureAlgorithm, "pkcs1v15")
+			}
+		})
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name:    "missing address",
+			cfg:     Config{Token: "s.static", KeyName: "github-app"},
+			wantErr: true,
+		},
+		{
+			name:    "missing key name",
+			cfg:     Config{Address: "http://127.0.0.1:8200", Token: "s.static"},
+			wantErr: true,
+		},
+		{
+			name:    "missing credentials",
+			cfg:     Config{Address: "http://127.0.0.1:8200", KeyName: "github-app"},
+			wantErr: true,
+		},
+		{
+			name:    "secret ID without role ID",
+			cfg:     Config{Address: "http://127.0.0.1:8200", SecretID: "secret", KeyName: "github-app"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSigner(context.Background(), tt.cfg)
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewSigner_Key(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		keyType string
+		wantErr string
+	}{
+		{
+			name:    "wrong token",
+			cfg:     Config{Token: "s.wrong", KeyName: "github-app"},
+			keyType: "rsa-2048",
+			wantErr: "permission denied",
+		},
+		{
+			name:    "unknown key",
+			cfg:     Config{Token: "s.static", KeyName: "other"},
+			keyType: "rsa-2048",
+			wantErr: "404",
+		},
+		{
+			name:    "ECDSA key",
+			cfg:     Config{Token: "s.static", KeyName: "github-app"},
+			keyType: "ecdsa-p256",
+			wantErr: `has type "ecdsa-p256"`,
+		},
+		{
+			name:    "Ed25519 key",
+			cfg:     Config{Token: "s.static", KeyName: "github-app"},
+			keyType: "ed25519",
+			wantErr: `has type "ed25519"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTransit(t)
+			fake.keyType = tt.keyType
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			tt.cfg.Address = srv.URL
+			_, err := NewSigner(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSigner_AppRoleLoginFails(t *testing.T) {
+	srv := httptest.NewServer(newFakeTransit(t))
+	defer srv.Close()
+
+	_, err := NewSigner(context.Background(), Config{Address: srv.URL, RoleID: "role", SecretID: "wrong", KeyName: "github-app"})
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid role or secret ID") {
+		t.Errorf("error should contain the vault error message, got %q", err)
+	}
+}
+
+func TestSigner_Close(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantRevoked bool
+	}{
+		{
+			name:        "revokes approle token",
+			cfg:         Config{RoleID: "role", SecretID: "secret", KeyName: "github-app"},
+			wantRevoked: true,
+		},
+		{
+			name:        "keeps caller's token",
+			cfg:         Config{Token: "s.static", KeyName: "github-app"},
+			wantRevoked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTransit(t)
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			tt.cfg.Address = srv.URL
+			s, err := NewSigner(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fake.revoked != tt.wantRevoked {
+				t.Errorf("revoked = %v, want %v", fake.revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestSigner_KeyID(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "defaults",
+			cfg:  Config{Token: "s.static", KeyName: "github-app"},
+			want: "transit/keys/github-app",
+		},
+		{
+			name: "custom mount and pinned version",
+			cfg:  Config{Token: "s.static", TransitMount: "/ci-transit/", KeyName: "github-app", KeyVersion: 3},
+			want: "ci-transit/keys/github-app/versions/3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(newFakeTransit(t))
+			defer srv.Close()
+
+			tt.cfg.Address = srv.URL
+			s, err := NewSigner(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.KeyID(); got != tt.want {
+				t.Errorf("KeyID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "v1", input: "vault:v1:" + base64.StdEncoding.EncodeToString([]byte("sig")), want: "sig"},
+		{name: "v12", input: "vault:v12:" + base64.StdEncoding.EncodeToString([]byte("sig")), want: "sig"},
+		{name: "missing prefix", input: "v1:c2ln", wantErr: true},
+		{name: "wrong prefix", input: "kms:v1:c2ln", wantErr: true},
+		{name: "bad version", input: "vault:1:c2ln", wantErr: true},
+		{name: "bad base64", input: "vault:v1:!!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSignature(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeSignature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
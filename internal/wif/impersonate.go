@@ -0,0 +1,70 @@
+package wif
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials/impersonate"
+	"cloud.google.com/go/auth/httptransport"
+)
+
+// serviceAccountPrefix is the prefix of service account resource names, which
+// the impersonate package adds to the emails it is given.
+const serviceAccountPrefix = "projects/-/serviceAccounts/"
+
+// ImpersonateConfig configures service account impersonation.
+type ImpersonateConfig struct {
+	// Base authenticates the impersonation requests. When nil, Application
+	// Default Credentials are used.
+	Base *auth.Credentials
+	// TargetPrincipal is the email of the service account to impersonate.
+	TargetPrincipal string
+	// Delegates is the chain of service accounts to hop through before
+	// TargetPrincipal, each granted roles/iam.serviceAccountTokenCreator on
+	// the next. Each entry is an email or "projects/-/serviceAccounts/EMAIL".
+	Delegates []string
+
+	// HTTPClient, when set, sends the IAM Credentials requests, which are
+	// authenticated with Base unless it is nil.
+	HTTPClient *http.Client
+}
+
+// Impersonate returns short-lived credentials of cfg.TargetPrincipal, which
+// are refreshed before they expire.
+func Impersonate(cfg ImpersonateConfig) (*auth.Credentials, error) {
+	if cfg.TargetPrincipal == "" {
+		return nil, errors.New("service account to impersonate is required")
+	}
+
+	delegates := make([]string, 0, len(cfg.Delegates))
+	for _, d := range cfg.Delegates {
+		delegates = append(delegates, strings.TrimPrefix(d, serviceAccountPrefix))
+	}
+
+	var client *http.Client
+	if cfg.HTTPClient != nil {
+		c := *cfg.HTTPClient
+		client = &c
+		if cfg.Base != nil {
+			if err := httptransport.AddAuthorizationMiddleware(client, cfg.Base); err != nil {
+				return nil, fmt.Errorf("failed to impersonate service account %s: %w", cfg.TargetPrincipal, err)
+			}
+		}
+	}
+
+	creds, err := impersonate.NewCredentials(&impersonate.CredentialsOptions{
+		TargetPrincipal: strings.TrimPrefix(cfg.TargetPrincipal, serviceAccountPrefix),
+		Scopes:          []string{cloudPlatformScope},
+		Delegates:       delegates,
+		Credentials:     cfg.Base,
+		Client:          client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate service account %s: %w", cfg.TargetPrincipal, err)
+	}
+
+	return creds, nil
+}
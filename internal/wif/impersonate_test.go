@@ -0,0 +1,146 @@
+package wif
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/auth"
+)
+
+const testTarget = "signer@my-project.iam.gserviceaccount.com"
+
+type staticTokenProvider string
+
+func (p staticTokenProvider) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{Value: string(p), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+// redirectTransport sends every request to the server at target, keeping its
+// path, so that the fixed IAM Credentials endpoint reaches a stand-in.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// fakeIAMCredentials stands in for the IAM Credentials generateAccessToken
+// method and records the delegates of the last request.
+func fakeIAMCredentials(t *testing.T, delegates *[]string) *http.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/v1/projects/-/serviceAccounts/"+testTarget+":generateAccessToken"; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+			http.Error(w, "unexpected path", http.StatusNotFound)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer base-token" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer base-token")
+		}
+
+		var req struct {
+			Delegates []string `json:"delegates"`
+			Scope     []string `json:"scope"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !slices.Equal(req.Scope, []string{cloudPlatformScope}) {
+			t.Errorf("scope = %q, want %q", req.Scope, cloudPlatformScope)
+		}
+		*delegates = req.Delegates
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"accessToken": "impersonated-token",
+			"expireTime":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: redirectTransport{target: target}}
+}
+
+func TestImpersonate(t *testing.T) {
+	base := auth.NewCredentials(&auth.CredentialsOptions{TokenProvider: staticTokenProvider("base-token")})
+
+	tests := []struct {
+		name          string
+		cfg           ImpersonateConfig
+		wantDelegates []string
+		wantErr       bool
+	}{
+		{
+			name: "direct",
+			cfg:  ImpersonateConfig{TargetPrincipal: testTarget},
+		},
+		{
+			name: "target as a resource name",
+			cfg:  ImpersonateConfig{TargetPrincipal: "projects/-/serviceAccounts/" + testTarget},
+		},
+		{
+			name: "delegation chain",
+			cfg: ImpersonateConfig{
+				TargetPrincipal: testTarget,
+				Delegates:       []string{"hop1@my-project.iam.gserviceaccount.com", "projects/-/serviceAccounts/hop2@my-project.iam.gserviceaccount.com"},
+			},
+			wantDelegates: []string{
+				"projects/-/serviceAccounts/hop1@my-project.iam.gserviceaccount.com",
+				"projects/-/serviceAccounts/hop2@my-project.iam.gserviceaccount.com",
+			},
+		},
+		{
+			name:    "empty target",
+			cfg:     ImpersonateConfig{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delegates []string
+			tt.cfg.Base = base
+			tt.cfg.HTTPClient = fakeIAMCredentials(t, &delegates)
+
+			creds, err := Impersonate(tt.cfg)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			token, err := creds.Token(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token.Value != "impersonated-token" {
+				t.Errorf("token = %q, want %q", token.Value, "impersonated-token")
+			}
+			if !slices.Equal(delegates, tt.wantDelegates) {
+				t.Errorf("delegates = %q, want %q", delegates, tt.wantDelegates)
+			}
+		})
+	}
+}
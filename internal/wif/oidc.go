@@ -0,0 +1,78 @@
+package wif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"cloud.google.com/go/auth/credentials/externalaccount"
+)
+
+// oidcTokenSupplier requests an ID token from the GitHub Actions runtime.
+// It implements externalaccount.SubjectTokenProvider.
+type oidcTokenSupplier struct {
+	client       *http.Client
+	requestURL   string
+	requestToken string
+	audience     string
+}
+
+func newOIDCTokenSupplier(client *http.Client, audience string) (*oidcTokenSupplier, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return nil, errors.New("GitHub Actions OIDC token is not available: grant the job the id-token: write permission")
+	}
+
+	return &oidcTokenSupplier{
+		client:       client,
+		requestURL:   requestURL,
+		requestToken: requestToken,
+		audience:     audience,
+	}, nil
+}
+
+func (s *oidcTokenSupplier) SubjectToken(ctx context.Context, _ *externalaccount.RequestOptions) (string, error) {
+	u, err := url.Parse(s.requestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("audience", s.audience)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request OIDC token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to request OIDC token: %s, body: %s", resp.Status, string(body))
+	}
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC token response: %w", err)
+	}
+	if out.Value == "" {
+		return "", errors.New("OIDC token response has no value")
+	}
+
+	return out.Value, nil
+}
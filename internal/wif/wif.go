@@ -0,0 +1,106 @@
+// Package wif authenticates to Google Cloud with Workload Identity Federation,
+// exchanging the GitHub Actions OIDC token at the Security Token Service and
+// optionally impersonating a service account. No credentials are written to
+// disk. It also provides a service account impersonation chain on top of any
+// credentials.
+package wif
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials/externalaccount"
+)
+
+// Environment variables that override the Google Cloud endpoints, e.g. to
+// point them at local stand-ins in tests.
+const (
+	STSEndpointEnv            = "GHAT_STS_ENDPOINT"
+	IAMCredentialsEndpointEnv = "GHAT_IAM_CREDENTIALS_ENDPOINT"
+)
+
+const (
+	defaultSTSEndpoint            = "https://sts.googleapis.com"
+	defaultIAMCredentialsEndpoint = "https://iamcredentials.googleapis.com"
+
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	jwtTokenType       = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+var providerPattern = regexp.MustCompile(`^projects/[0-9]+/locations/global/workloadIdentityPools/[^/]+/providers/[^/]+$`)
+
+// Config configures Workload Identity Federation.
+type Config struct {
+	// WorkloadIdentityProvider is the full resource name of the provider, e.g.
+	// "projects/123456789/locations/global/workloadIdentityPools/my-pool/providers/my-provider".
+	WorkloadIdentityProvider string
+	// ServiceAccount is the email of the service account to impersonate.
+	// When empty, the federated token is used directly.
+	ServiceAccount string
+
+	// STSEndpoint and IAMCredentialsEndpoint override the Google endpoints.
+	// When empty, GHAT_STS_ENDPOINT and GHAT_IAM_CREDENTIALS_ENDPOINT are
+	// used, falling back to the public endpoints.
+	STSEndpoint            string
+	IAMCredentialsEndpoint string
+
+	// HTTPClient is used for the OIDC, STS and IAM Credentials requests.
+	HTTPClient *http.Client
+}
+
+// NewCredentials returns credentials backed by the GitHub Actions OIDC token.
+// The job needs the id-token: write permission.
+func NewCredentials(cfg Config) (*auth.Credentials, error) {
+	if cfg.WorkloadIdentityProvider == "" {
+		return nil, errors.New("workload identity provider is required")
+	}
+	if !providerPattern.MatchString(cfg.WorkloadIdentityProvider) {
+		return nil, fmt.Errorf("invalid workload identity provider %q: expected projects/{project_number}/locations/global/workloadIdentityPools/{pool}/providers/{provider}", cfg.WorkloadIdentityProvider)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	supplier, err := newOIDCTokenSupplier(client, "https://iam.googleapis.com/"+cfg.WorkloadIdentityProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &externalaccount.Options{
+		Audience:             "//iam.googleapis.com/" + cfg.WorkloadIdentityProvider,
+		SubjectTokenType:     jwtTokenType,
+		TokenURL:             endpoint(cfg.STSEndpoint, STSEndpointEnv, defaultSTSEndpoint) + "/v1/token",
+		Scopes:               []string{cloudPlatformScope},
+		SubjectTokenProvider: supplier,
+		Client:               client,
+	}
+	if cfg.ServiceAccount != "" {
+		opts.ServiceAccountImpersonationURL = fmt.Sprintf("%s/v1/projects/-/serviceAccounts/%s:generateAccessToken",
+			endpoint(cfg.IAMCredentialsEndpoint, IAMCredentialsEndpointEnv, defaultIAMCredentialsEndpoint), cfg.ServiceAccount)
+	}
+
+	creds, err := externalaccount.NewCredentials(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workload identity credentials: %w", err)
+	}
+
+	return creds, nil
+}
+
+func endpoint(value, env, def string) string {
+	if value == "" {
+		value = os.Getenv(env)
+	}
+	if value == "" {
+		value = def
+	}
+	return strings.TrimSuffix(value, "/")
+}
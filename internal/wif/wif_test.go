@@ -0,0 +1,145 @@
+package wif
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testProvider       = "projects/123456789/locations/global/workloadIdentityPools/github/providers/actions"
+	testServiceAccount = "ghat@my-project.iam.gserviceaccount.com"
+)
+
+// fakeGoogle stands in for the Actions OIDC endpoint, STS and IAM Credentials.
+func fakeGoogle(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/oidc":
+			if got := r.Header.Get("Authorization"); got != "Bearer request-token" {
+				t.Errorf("OIDC Authorization = %q", got)
+			}
+			if got, want := r.URL.Query().Get("audience"), "https://iam.googleapis.com/"+testProvider; got != want {
+				t.Errorf("OIDC audience = %q, want %q", got, want)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"value": "oidc-jwt"})
+
+		case "/v1/token":
+			if err := r.ParseForm(); err != nil {
+				t.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if got := r.PostForm.Get("subject_token"); got != "oidc-jwt" {
+				t.Errorf("subject_token = %q, want %q", got, "oidc-jwt")
+			}
+			if got, want := r.PostForm.Get("audience"), "//iam.googleapis.com/"+testProvider; got != want {
+				t.Errorf("audience = %q, want %q", got, want)
+			}
+			if got := r.PostForm.Get("subject_token_type"); got != jwtTokenType {
+				t.Errorf("subject_token_type = %q, want %q", got, jwtTokenType)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token":      "federated-token",
+				"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+				"token_type":        "Bearer",
+				"expires_in":        3600,
+			})
+
+		case "/v1/projects/-/serviceAccounts/" + testServiceAccount + ":generateAccessToken":
+			if got := r.Header.Get("Authorization"); got != "Bearer federated-token" {
+				t.Errorf("IAM Authorization = %q", got)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"accessToken": "impersonated-token",
+				"expireTime":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			})
+
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestNewCredentials(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceAccount string
+		wantToken      string
+	}{
+		{
+			name:      "federated token",
+			wantToken: "federated-token",
+		},
+		{
+			name:           "service account impersonation",
+			serviceAccount: testServiceAccount,
+			wantToken:      "impersonated-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeGoogle(t)
+			t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", srv.URL+"/oidc?api-version=2.0")
+			t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+			t.Setenv(STSEndpointEnv, srv.URL)
+
+			creds, err := NewCredentials(Config{
+				WorkloadIdentityProvider: testProvider,
+				ServiceAccount:           tt.serviceAccount,
+				IAMCredentialsEndpoint:   srv.URL,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			token, err := creds.Token(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token.Value != tt.wantToken {
+				t.Errorf("token = %q, want %q", token.Value, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestNewCredentials_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		oidc     bool
+	}{
+		{name: "empty provider", provider: "", oidc: true},
+		{name: "pool instead of provider", provider: "projects/123456789/locations/global/workloadIdentityPools/github", oidc: true},
+		{name: "project ID instead of number", provider: "projects/my-project/locations/global/workloadIdentityPools/github/providers/actions", oidc: true},
+		{name: "no OIDC token", provider: testProvider},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+			t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+			if tt.oidc {
+				t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "http://localhost/oidc")
+				t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+			}
+
+			if _, err := NewCredentials(Config{WorkloadIdentityProvider: tt.provider}); err == nil {
+				t.Error("expected error but got nil")
+			}
+		})
+	}
+}
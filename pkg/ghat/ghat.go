@@ -1,5 +1,7 @@
 // Package ghat provides a public API for generating and revoking GitHub App
-// installation access tokens using Google Cloud KMS for JWT signing.
+// installation access tokens using Google Cloud KMS, AWS KMS, HashiCorp Vault
+// Transit, Azure Key Vault, a PKCS#11 token, an ssh-agent, or a local private
+// key for JWT signing.
 //
 // Typical usage:
 //
@@ -7,61 +9,161 @@
 //	if err != nil { ... }
 //	defer signer.Close()
 //
-//	app := ghat.New(appID, signer, "")
+//	app := ghat.New(clientID, signer, "")
 //	token, err := app.CreateGitHubAppToken(ctx, owner, nil, nil)
 //	if err != nil { ... }
 //	// use token ...
 //	if err := app.RevokeGitHubAppToken(ctx, token); err != nil { ... }
+//
+// Signing keys held elsewhere, such as in an HSM, can be used by passing any
+// JWTSigner implementation to New.
 package ghat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/yagihash/ghat/v2/internal/client"
-	"github.com/yagihash/ghat/v2/internal/jwt"
+	"github.com/yagihash/ghat/v2/internal/failover"
 )
 
-// signerIface is the minimal interface required by App for JWT signing.
-// *Signer.inner (*kms.Signer) satisfies this interface.
-type signerIface interface {
+// JWTSigner signs GitHub App JWTs. *Signer implements it for the built-in
+// backends; implement it to plug in other key stores such as an HSM.
+type JWTSigner interface {
+	// Sign returns the signature of data. For RS256, data is hashed with
+	// SHA-256 and signed with RSASSA-PKCS1-v1_5.
 	Sign(ctx context.Context, data []byte) ([]byte, error)
+	// Algorithm returns the JWS algorithm of the signatures, e.g. "RS256".
+	// GitHub only accepts RS256.
+	Algorithm() string
+	// KeyID returns an identifier of the signing key, used in error messages.
+	KeyID() string
 }
 
 // App orchestrates GitHub App JWT signing, token issuance, and token revocation.
 type App struct {
-	appID   string
-	baseURL string
-	signer  signerIface
+	issuer     string
+	baseURL    string
+	signer     JWTSigner
+	jwtOptions JWTOptions
+	jwts       jwtCache
+	timeout    time.Duration
+	retry      RetryPolicy
+}
+
+// RetryPolicy controls how GitHub API requests that fail with a network
+// error, a 5xx response, or a rate limit response are retried. Retry-After and
+// X-RateLimit-Reset are honored up to MaxWait. Zero fields use the defaults:
+// 3 attempts with a backoff with jitter from 500ms up to 5s, and a MaxWait of
+// one minute.
+type RetryPolicy = client.RetryPolicy
+
+// APIError is returned, wrapped, for unexpected GitHub API responses. Use
+// errors.As to inspect it: a 404 StatusCode when resolving the installation
+// means the App is not installed for the owner, a 422 when creating the token
+// means a requested permission or repository is not granted to the
+// installation, and a 401 means GitHub rejected the JWT, e.g. because of bad
+// credentials. RequestID is the X-GitHub-Request-Id to quote to GitHub Support.
+type APIError = client.APIError
+
+// ValidationError is an entry of APIError.Errors.
+type ValidationError = client.ValidationError
+
+// JWTOptions overrides the claims of the App JWT. Zero fields use the
+// defaults of a 10 minute expiry and an iat claim 60 seconds in the past.
+type JWTOptions struct {
+	// Expiry is the validity of the JWT. GitHub accepts at most 10 minutes.
+	Expiry time.Duration
+	// IssuedAtSkew is the offset of the iat claim from the current time,
+	// which must not be positive.
+	IssuedAtSkew time.Duration
 }
 
 // New constructs an App.
-// signer must be obtained from NewSigner.
+// clientID is the Client ID of the GitHub App, which GitHub recommends as
+// the JWT issuer; its numeric App ID is accepted too.
+// signer is typically obtained from NewSigner, but any JWTSigner can be used.
 // baseURL is the GitHub API base URL; pass "" to use "https://api.github.com".
-func New(appID string, signer *Signer, baseURL string) *App {
+func New(clientID string, signer JWTSigner, baseURL string) *App {
 	if baseURL == "" {
 		baseURL = "https://api.github.com"
 	}
 	return &App{
-		appID:   appID,
+		issuer:  clientID,
 		baseURL: baseURL,
-		signer:  signer.inner,
+		signer:  signer,
 	}
 }
 
-// newApp is the internal constructor used by tests to inject a mock signer.
-func newApp(appID string, signer signerIface, baseURL string) *App {
-	if baseURL == "" {
-		baseURL = "https://api.github.com"
-	}
-	return &App{
-		appID:   appID,
-		baseURL: baseURL,
-		signer:  signer,
+// SetJWTOptions overrides the claims of the JWTs signed by a and drops the
+// cached JWTs. It must not be called concurrently with CreateGitHubAppToken.
+func (a *App) SetJWTOptions(o JWTOptions) {
+	a.jwtOptions = o
+	a.jwts.reset()
+}
+
+// SetTimeout sets the timeout of each GitHub API request, which defaults to
+// 10 seconds. Requests are also canceled with the context passed to the
+// methods of a. It must not be called concurrently with other methods.
+func (a *App) SetTimeout(d time.Duration) {
+	a.timeout = d
+}
+
+// SetRetryPolicy sets the retry policy of GitHub API requests. It must not be
+// called concurrently with other methods.
+func (a *App) SetRetryPolicy(p RetryPolicy) {
+	a.retry = p
+}
+
+// newClient returns a GitHub API client authenticating with token.
+func (a *App) newClient(token string) *client.Client {
+	c := client.New(a.baseURL, token)
+	if a.timeout != 0 {
+		c.HTTPClient.Timeout = a.timeout
 	}
+	c.Retry = a.retry
+	return c
 }
 
-// CreateGitHubAppToken generates a signed JWT, resolves the GitHub App installation for
+// Token is an installation access token along with what GitHub granted it.
+type Token struct {
+	// Token is the installation access token.
+	Token string
+	// ExpiresAt is when the token expires, one hour after it was issued.
+	ExpiresAt time.Time
+	// Permissions maps the permissions granted to the token to their access
+	// levels. They are those of the installation when none were requested.
+	Permissions map[string]string
+	// RepositorySelection is "all" when the token can access every
+	// repository of the installation, and "selected" otherwise.
+	RepositorySelection string
+	// Repositories are the repositories the token is scoped to. It is empty
+	// when RepositorySelection is "all".
+	Repositories []Repository
+}
+
+// Repository identifies a repository a Token is scoped to.
+type Repository = client.Repository
+
+// TokenRequest scopes the installation access token created by
+// CreateInstallationToken. The zero value requests the default permissions of
+// the installation for all the repositories it can access.
+type TokenRequest struct {
+	// Permissions maps permission names to access levels, e.g.
+	// "contents": "read".
+	Permissions map[string]string
+	// Repositories are the names of the repositories to scope the token to.
+	Repositories []string
+	// RepositoryIDs are the IDs of the repositories to scope the token to.
+	// Unlike names, they survive renames and transfers. They can be combined
+	// with Repositories.
+	RepositoryIDs []int64
+}
+
+// CreateGitHubAppToken signs a JWT, or reuses the one signed by an earlier
+// call until shortly before it expires, resolves the GitHub App installation for
 // the given owner, and returns an installation access token.
 // This satisfies requirement 3: GitHub App Token issuance.
 //
@@ -70,32 +172,111 @@ func newApp(appID string, signer signerIface, baseURL string) *App {
 //
 // repositories is an optional list of repository names to scope the token to.
 // Pass nil to grant access to all repositories the installation can access.
+//
+// When GitHub rejects the JWT because the local clock is off, the JWT is
+// rebuilt with the time from GitHub's Date header and the request retried once.
+//
+// With a signer from NewFailoverSigner, the next key is tried when signing
+// fails with a retryable error or GitHub rejects the JWT.
 func (a *App) CreateGitHubAppToken(ctx context.Context, owner string, permissions map[string]string, repositories []string) (string, error) {
-	signedJWT, err := jwt.Build(ctx, a.signer, a.appID, time.Now())
+	token, err := a.CreateInstallationToken(ctx, owner, TokenRequest{
+		Permissions:  permissions,
+		Repositories: repositories,
+	})
 	if err != nil {
 		return "", err
 	}
 
-	c := client.New(a.baseURL, signedJWT)
+	return token.Token, nil
+}
+
+// CreateInstallationToken is like CreateGitHubAppToken, but also accepts
+// repository IDs to scope the token to, and returns the expiry, permissions
+// and repositories of the token.
+func (a *App) CreateInstallationToken(ctx context.Context, owner string, req TokenRequest) (*Token, error) {
+	var signer failover.Signer = a.signer
+	if s, ok := a.signer.(*Signer); ok {
+		signer = s.inner
+	}
 
-	installation, err := c.GetInstallationByOwner(owner)
+	var token *Token
+	err := failover.Try(ctx, signer, func(s failover.Signer) error {
+		var err error
+		token, err = a.createToken(ctx, s, owner, req)
+		return err
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	accessToken, err := c.GetInstallationAccessToken(installation.ID, permissions, repositories)
+	return token, nil
+}
+
+// createToken issues an installation access token with a JWT signed by
+// signer. A JWT rejected by GitHub is dropped from the cache, and when it was
+// rejected because the local clock is off, a new one is signed with GitHub's
+// time and the request retried once.
+func (a *App) createToken(ctx context.Context, signer failover.Signer, owner string, req TokenRequest) (*Token, error) {
+	if alg := signer.Algorithm(); alg != "RS256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q of key %s: GitHub requires RS256", alg, signer.KeyID())
+	}
+
+	signedJWT, err := a.jwts.get(ctx, signer, a.issuer, a.jwtOptions, false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return accessToken.Token, nil
+	token, err := a.requestToken(ctx, signedJWT, owner, req)
+	if errors.Is(err, client.ErrUnauthorized) {
+		a.jwts.forget(signer)
+	}
+
+	var skew *client.ClockSkewError
+	if errors.As(err, &skew) {
+		a.jwts.setClockOffset(skew.Offset)
+
+		signedJWT, err := a.jwts.get(ctx, signer, a.issuer, a.jwtOptions, true)
+		if err != nil {
+			return nil, err
+		}
+		token, err := a.requestToken(ctx, signedJWT, owner, req)
+		if errors.Is(err, client.ErrUnauthorized) {
+			a.jwts.forget(signer)
+		}
+		return token, err
+	}
+
+	return token, err
+}
+
+// requestToken issues an installation access token with signedJWT.
+func (a *App) requestToken(ctx context.Context, signedJWT, owner string, req TokenRequest) (*Token, error) {
+	c := a.newClient(signedJWT)
+
+	installation, err := c.GetInstallationByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := c.GetInstallationAccessToken(ctx, installation.ID, req.Permissions, req.Repositories, req.RepositoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{
+		Token:               accessToken.Token,
+		ExpiresAt:           accessToken.ExpiresAt,
+		Permissions:         accessToken.Permissions,
+		RepositorySelection: accessToken.RepositorySelection,
+		Repositories:        accessToken.Repositories,
+	}, nil
 }
 
 // RevokeGitHubAppToken revokes an installation access token.
 // This satisfies requirement 4: GitHub App Token revocation.
 //
-// token is the value previously returned by CreateGitHubAppToken.
+// token is the value previously returned by CreateGitHubAppToken, or the
+// Token field of the result of CreateInstallationToken.
 func (a *App) RevokeGitHubAppToken(ctx context.Context, token string) error {
-	c := client.New(a.baseURL, token)
-	return c.DeleteInstallationAccessToken()
+	return a.newClient(token).DeleteInstallationAccessToken(ctx)
 }
@@ -2,25 +2,40 @@ package ghat
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
-// mockSigner satisfies signerIface for tests without requiring real KMS.
+// mockSigner satisfies JWTSigner for tests without requiring real KMS.
 type mockSigner struct {
 	signFn func(ctx context.Context, data []byte) ([]byte, error)
+	alg    string
 }
 
 func (m *mockSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
 	return m.signFn(ctx, data)
 }
 
+func (m *mockSigner) Algorithm() string {
+	if m.alg == "" {
+		return "RS256"
+	}
+	return m.alg
+}
+
+func (m *mockSigner) KeyID() string {
+	return "mock-key"
+}
+
 // fakeSig is a minimal signature that produces a valid base64url encoding.
 var fakeSig = []byte("fakesig")
 
@@ -36,6 +51,9 @@ func failingSigner(errMsg string) *mockSigner {
 	}}
 }
 
+// fastRetry keeps the backoff of retried requests short in tests.
+var fastRetry = RetryPolicy{Initial: time.Millisecond, Max: time.Millisecond}
+
 // newResponse builds an *http.Response suitable for use in httptest handlers.
 func jsonResponse(w http.ResponseWriter, statusCode int, body string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -45,7 +63,7 @@ func jsonResponse(w http.ResponseWriter, statusCode int, body string) {
 
 func TestNew_DefaultBaseURL(t *testing.T) {
 	s := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) { return fakeSig, nil }}
-	app := newApp("123", s, "")
+	app := New("123", s, "")
 	if app.baseURL != "https://api.github.com" {
 		t.Errorf("baseURL = %q, want %q", app.baseURL, "https://api.github.com")
 	}
@@ -53,7 +71,7 @@ func TestNew_DefaultBaseURL(t *testing.T) {
 
 func TestNew_CustomBaseURL(t *testing.T) {
 	s := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) { return fakeSig, nil }}
-	app := newApp("123", s, "https://github.example.com/api/v3")
+	app := New("123", s, "https://github.example.com/api/v3")
 	if app.baseURL != "https://github.example.com/api/v3" {
 		t.Errorf("baseURL = %q, want %q", app.baseURL, "https://github.example.com/api/v3")
 	}
@@ -94,6 +112,16 @@ func TestApp_CreateGitHubAppToken(t *testing.T) {
 			owner:   "myorg",
 			wantErr: true,
 		},
+		{
+			name: "unsupported algorithm",
+			signer: &mockSigner{
+				signFn: func(ctx context.Context, data []byte) ([]byte, error) { return fakeSig, nil },
+				alg:    "PS256",
+			},
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			owner:   "myorg",
+			wantErr: true,
+		},
 		{
 			name:   "installation not found",
 			signer: successfulSigner(),
@@ -158,7 +186,7 @@ func TestApp_CreateGitHubAppToken(t *testing.T) {
 			srv := httptest.NewServer(tt.handler)
 			defer srv.Close()
 
-			app := newApp("12345", tt.signer, srv.URL)
+			app := New("12345", tt.signer, srv.URL)
 			got, err := app.CreateGitHubAppToken(context.Background(), tt.owner, tt.permissions, tt.repos)
 
 			if tt.wantErr {
@@ -177,6 +205,218 @@ func TestApp_CreateGitHubAppToken(t *testing.T) {
 	}
 }
 
+func TestApp_SetJWTOptions(t *testing.T) {
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil || json.Unmarshal(payload, &claims) != nil {
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_testtoken"}`)
+		}
+	}))
+	defer srv.Close()
+
+	app := New("Iv23liABCDEF", successfulSigner(), srv.URL)
+	app.SetJWTOptions(JWTOptions{Expiry: 5 * time.Minute, IssuedAtSkew: -30 * time.Second})
+
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Iss != "Iv23liABCDEF" {
+		t.Errorf("iss = %q, want %q", claims.Iss, "Iv23liABCDEF")
+	}
+	if got := claims.Exp - claims.Iat; got != 330 {
+		t.Errorf("exp - iat = %d, want 330", got)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_ClockSkew(t *testing.T) {
+	// GitHub's clock is two hours ahead, so JWTs built with the local time
+	// have already expired.
+	serverNow := time.Now().Add(2 * time.Hour)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Date", serverNow.UTC().Format(http.TimeFormat))
+
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Exp int64 `json:"exp"`
+		}
+		_ = json.Unmarshal(payload, &claims)
+		if claims.Exp < serverNow.Unix() {
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "'Expiration time' claim ('exp') must be a numeric value representing the future time at which the assertion expires"}`)
+			return
+		}
+
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_testtoken"}`)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := New("12345", successfulSigner(), srv.URL).CreateGitHubAppToken(context.Background(), "myorg", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ghs_testtoken" {
+		t.Errorf("token = %q, want %q", got, "ghs_testtoken")
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_Failover(t *testing.T) {
+	// GitHub only knows the previous key, as during a key rotation.
+	previous := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("previous"), nil
+	}}
+	current := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("current"), nil
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.Header.Get("Authorization"), ".cHJldmlvdXM") {
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_previous"}`)
+		}
+	}))
+	defer srv.Close()
+
+	signer, err := NewFailoverSigner(current, previous)
+	if err != nil {
+		t.Fatalf("NewFailoverSigner: %v", err)
+	}
+
+	got, err := New("12345", signer, srv.URL).CreateGitHubAppToken(context.Background(), "myorg", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ghs_previous" {
+		t.Errorf("token = %q, want %q", got, "ghs_previous")
+	}
+
+	// Without a failover signer, the rejection is returned.
+	if _, err := New("12345", current, srv.URL).CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err == nil {
+		t.Error("expected error but got nil")
+	}
+}
+
+func TestApp_SetTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	app := New("12345", successfulSigner(), srv.URL)
+	app.SetTimeout(10 * time.Millisecond)
+	app.SetRetryPolicy(fastRetry)
+
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err == nil {
+		t.Error("expected error but got nil")
+	}
+
+	// Requests are also canceled with the context.
+	app.SetTimeout(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := app.RevokeGitHubAppToken(ctx, "ghs_sometoken"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestApp_CreateInstallationToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			var req struct {
+				RepositoryIDs []int64 `json:"repository_ids"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.RepositoryIDs) != 1 || req.RepositoryIDs[0] != 1296269 {
+				http.Error(w, fmt.Sprintf("unexpected repository_ids: %v", req.RepositoryIDs), http.StatusBadRequest)
+				return
+			}
+			jsonResponse(w, http.StatusCreated, `{
+				"token": "ghs_testtoken",
+				"expires_at": "2026-10-18T12:00:00Z",
+				"permissions": {"contents": "read"},
+				"repository_selection": "selected",
+				"repositories": [{"id": 1296269, "name": "Hello-World", "full_name": "octocat/Hello-World"}]
+			}`)
+		default:
+			http.Error(w, "unexpected path: "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	token, err := New("12345", successfulSigner(), srv.URL).CreateInstallationToken(context.Background(), "myorg", TokenRequest{
+		Permissions:   map[string]string{"contents": "read"},
+		Repositories:  []string{"Hello-World"},
+		RepositoryIDs: []int64{1296269},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &Token{
+		Token:               "ghs_testtoken",
+		ExpiresAt:           time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Permissions:         map[string]string{"contents": "read"},
+		RepositorySelection: "selected",
+		Repositories:        []Repository{{ID: 1296269, Name: "Hello-World", FullName: "octocat/Hello-World"}},
+	}
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("CreateInstallationToken() = %+v, want %+v", token, want)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "0400:1234:5678")
+		jsonResponse(w, http.StatusNotFound, `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`)
+	}))
+	defer srv.Close()
+
+	_, err := New("12345", successfulSigner(), srv.URL).CreateGitHubAppToken(context.Background(), "myorg", nil, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, http.StatusNotFound)
+	}
+	if apiErr.DocumentationURL != "https://docs.github.com/rest" {
+		t.Errorf("DocumentationURL = %q, want %q", apiErr.DocumentationURL, "https://docs.github.com/rest")
+	}
+	if apiErr.RequestID != "0400:1234:5678" {
+		t.Errorf("RequestID = %q, want %q", apiErr.RequestID, "0400:1234:5678")
+	}
+}
+
 func TestApp_RevokeGitHubAppToken(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -224,7 +464,8 @@ func TestApp_RevokeGitHubAppToken(t *testing.T) {
 			defer srv.Close()
 
 			// RevokeGitHubAppToken only needs baseURL and token; signer is irrelevant.
-			app := newApp("12345", successfulSigner(), srv.URL)
+			app := New("12345", successfulSigner(), srv.URL)
+			app.SetRetryPolicy(fastRetry)
 			err := app.RevokeGitHubAppToken(context.Background(), tt.token)
 
 			if tt.wantErr {
@@ -0,0 +1,154 @@
+package ghat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/yagihash/ghat/v2/internal/failover"
+	"github.com/yagihash/ghat/v2/internal/jwt"
+)
+
+// jwtRefreshMargin is how long before its exp claim a cached JWT is replaced,
+// so that it does not expire while a request is in flight.
+const jwtRefreshMargin = time.Minute
+
+// jwtCache holds the signed JWT of each signer of an App until shortly before
+// it expires. Concurrent callers missing the cache share one signing call.
+type jwtCache struct {
+	mu sync.Mutex
+	// entries is keyed by the signer itself, since KeyID need not be unique.
+	// Signers that are not comparable, e.g. structs holding a func in an
+	// interface field, are not cached.
+	entries map[failover.Signer]*cachedJWT
+	// clockOffset is GitHub's time minus the local time, as last reported
+	// by a client.ClockSkewError.
+	clockOffset time.Duration
+}
+
+type cachedJWT struct {
+	token string
+	// refreshAt is the local time after which the JWT is signed again.
+	refreshAt time.Time
+	// group shares the signing calls of the signer, keyed by the clock
+	// offset they sign with.
+	group singleflight.Group
+}
+
+// get returns a JWT signed by signer, from the cache unless it is due for a
+// refresh or refresh is true.
+func (c *jwtCache) get(ctx context.Context, signer failover.Signer, issuer string, o JWTOptions, refresh bool) (string, error) {
+	if !reflect.ValueOf(signer).Comparable() {
+		return c.sign(ctx, nil, signer, issuer, o, c.offset())
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[failover.Signer]*cachedJWT)
+	}
+	e, ok := c.entries[signer]
+	if !ok {
+		e = &cachedJWT{}
+		c.entries[signer] = e
+	}
+	token, refreshAt, offset := e.token, e.refreshAt, c.clockOffset
+	c.mu.Unlock()
+
+	if !refresh && token != "" && time.Now().Before(refreshAt) {
+		return token, nil
+	}
+
+	// A refresh after a clock skew error must not join a call still signing
+	// with the previous offset. The signing call is shared, so it must not be
+	// canceled along with the context of the caller that happens to start it.
+	ch := e.group.DoChan(offset.String(), func() (any, error) {
+		return c.sign(context.WithoutCancel(ctx), e, signer, issuer, o, offset)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// sign builds a JWT signed by signer at the local time corrected by offset and
+// stores it in e, unless e is nil or the offset has changed since.
+func (c *jwtCache) sign(ctx context.Context, e *cachedJWT, signer failover.Signer, issuer string, o JWTOptions, offset time.Duration) (string, error) {
+	expiry := jwt.Expiry
+	var opts []jwt.Option
+	if o.Expiry != 0 {
+		expiry = o.Expiry
+		opts = append(opts, jwt.WithExpiry(o.Expiry))
+	}
+	if o.IssuedAtSkew != 0 {
+		opts = append(opts, jwt.WithIssuedAtSkew(o.IssuedAtSkew))
+	}
+
+	now := time.Now()
+	token, err := jwt.Build(ctx, signer, issuer, now.Add(offset), opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT with key %s: %w", signer.KeyID(), err)
+	}
+
+	if e == nil {
+		return token, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset != c.clockOffset {
+		return token, nil
+	}
+	e.token = token
+	e.refreshAt = now.Add(expiry - min(jwtRefreshMargin, expiry/2))
+
+	return token, nil
+}
+
+// forget drops the cached JWT of signer.
+func (c *jwtCache) forget(signer failover.Signer) {
+	if !reflect.ValueOf(signer).Comparable() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[signer]; ok {
+		e.token = ""
+	}
+}
+
+// offset returns the clock offset JWTs are signed with.
+func (c *jwtCache) offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.clockOffset
+}
+
+// setClockOffset makes JWTs signed from now on use GitHub's time.
+func (c *jwtCache) setClockOffset(offset time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clockOffset = offset
+}
+
+// reset drops all cached JWTs.
+func (c *jwtCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.entries {
+		e.token = ""
+	}
+}
@@ -0,0 +1,251 @@
+package ghat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSigner returns a mockSigner that counts its Sign calls and waits
+// for release, if not nil, before returning.
+func countingSigner(calls *atomic.Int32, release <-chan struct{}) *mockSigner {
+	return &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		return fakeSig, nil
+	}}
+}
+
+// tokenServer accepts every JWT, unless reject is set and returns true for
+// the Authorization header.
+func tokenServer(t *testing.T, reject func(auth string) bool) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject != nil && reject(r.Header.Get("Authorization")) {
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_testtoken"}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestApp_CreateGitHubAppToken_ReusesJWT(t *testing.T) {
+	var calls atomic.Int32
+	app := New("12345", countingSigner(&calls, nil), tokenServer(t, nil).URL)
+
+	for range 3 {
+		if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Sign calls = %d, want 1", got)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_RefreshesJWT(t *testing.T) {
+	var calls atomic.Int32
+	app := New("12345", countingSigner(&calls, nil), tokenServer(t, nil).URL)
+	// A 1s JWT is refreshed after 500ms.
+	app.SetJWTOptions(JWTOptions{Expiry: time.Second})
+
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(600 * time.Millisecond)
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Sign calls = %d, want 2", got)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_SingleFlight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	app := New("12345", countingSigner(&calls, release), tokenServer(t, nil).URL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() {
+			_, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil)
+			errs <- err
+		})
+	}
+	// Give the callers time to pile up behind the first signing call.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Sign calls = %d, want 1", got)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_DropsRejectedJWT(t *testing.T) {
+	var calls atomic.Int32
+	var reject atomic.Bool
+	reject.Store(true)
+	srv := tokenServer(t, func(string) bool { return reject.Load() })
+	app := New("12345", countingSigner(&calls, nil), srv.URL)
+
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+
+	reject.Store(false)
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Sign calls = %d, want 2", got)
+	}
+}
+
+func TestJWTCache_SignersWithSameKeyID(t *testing.T) {
+	// Both signers report the KeyID "mock-key".
+	a := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("a"), nil
+	}}
+	b := &mockSigner{signFn: func(ctx context.Context, data []byte) ([]byte, error) {
+		return []byte("b"), nil
+	}}
+
+	var c jwtCache
+	for _, tt := range []struct {
+		signer *mockSigner
+		suffix string
+	}{
+		{signer: a, suffix: ".YQ"},
+		{signer: b, suffix: ".Yg"},
+		{signer: a, suffix: ".YQ"},
+	} {
+		got, err := c.get(context.Background(), tt.signer, "12345", JWTOptions{}, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(got, tt.suffix) {
+			t.Errorf("JWT %q, want suffix %q", got, tt.suffix)
+		}
+	}
+}
+
+func TestJWTCache_RefreshDoesNotJoinStaleCall(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	signer := countingSigner(&calls, release)
+
+	var c jwtCache
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, _ = c.get(context.Background(), signer, "12345", JWTOptions{}, false)
+	})
+	waitForCalls(t, &calls, 1)
+
+	c.setClockOffset(time.Hour)
+	wg.Go(func() {
+		_, _ = c.get(context.Background(), signer, "12345", JWTOptions{}, true)
+	})
+	waitForCalls(t, &calls, 2)
+
+	close(release)
+	wg.Wait()
+}
+
+// waitForCalls waits until calls reaches want.
+func waitForCalls(t *testing.T, calls *atomic.Int32, want int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Sign calls = %d, want %d", calls.Load(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestApp_CreateGitHubAppToken_DropsRejectedRetryJWT(t *testing.T) {
+	var calls atomic.Int32
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			// GitHub's clock is two hours ahead.
+			w.Header().Set("Date", time.Now().Add(2*time.Hour).UTC().Format(http.TimeFormat))
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "'Expiration time' claim ('exp') must be a numeric value representing the future time at which the assertion expires"}`)
+			return
+		case 2:
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_testtoken"}`)
+		}
+	}))
+	defer srv.Close()
+	app := New("12345", countingSigner(&calls, nil), srv.URL)
+
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if _, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Sign calls = %d, want 3", got)
+	}
+}
+
+// funcSigner is comparable as a type, but its values are not when sign holds
+// a func.
+type funcSigner struct {
+	sign any
+}
+
+func (s funcSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	return s.sign.(func() []byte)(), nil
+}
+
+func (s funcSigner) Algorithm() string { return "RS256" }
+
+func (s funcSigner) KeyID() string { return "func-key" }
+
+func TestJWTCache_UnhashableSigner(t *testing.T) {
+	signer := funcSigner{sign: func() []byte { return fakeSig }}
+
+	var c jwtCache
+	for range 2 {
+		if _, err := c.get(context.Background(), signer, "12345", JWTOptions{}, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	c.forget(signer)
+}
@@ -2,28 +2,299 @@ package ghat
 
 import (
 	"context"
+	"fmt"
 
+	"google.golang.org/api/option"
+
+	"github.com/yagihash/ghat/v2/internal/awskms"
+	"github.com/yagihash/ghat/v2/internal/azurekv"
+	"github.com/yagihash/ghat/v2/internal/failover"
 	"github.com/yagihash/ghat/v2/internal/kms"
+	"github.com/yagihash/ghat/v2/internal/pkcs11"
+	"github.com/yagihash/ghat/v2/internal/privatekey"
+	"github.com/yagihash/ghat/v2/internal/sshagent"
+	"github.com/yagihash/ghat/v2/internal/vault"
+	"github.com/yagihash/ghat/v2/internal/wif"
 )
 
-// Signer signs data using a Google Cloud KMS asymmetric key.
-// It wraps the internal KMS implementation and is the entry point for
+// backend is implemented by the internal signer implementations.
+type backend interface {
+	Sign(ctx context.Context, data []byte) ([]byte, error)
+	Algorithm() string
+	KeyID() string
+	Close() error
+}
+
+// Signer signs data using a Google Cloud KMS asymmetric key, an AWS KMS key,
+// a HashiCorp Vault Transit key, an Azure Key Vault key, a PKCS#11 token, an
+// ssh-agent, or a local private key.
+// It wraps the internal implementations and is the entry point for
 // requirement 1 (KMS access) and requirement 2 (JWT signing).
 type Signer struct {
-	inner *kms.Signer
+	inner backend
 }
 
+var _ JWTSigner = (*Signer)(nil)
+
+// KMSIntegrityError is returned when a Cloud KMS AsymmetricSign or GetPublicKey
+// response fails its CRC32C or key name integrity checks. Use errors.As to
+// detect it.
+type KMSIntegrityError = kms.IntegrityError
+
+// KMSRetryConfig controls how a Signer backed by Google Cloud KMS retries
+// AsymmetricSign on Unavailable, DeadlineExceeded and ResourceExhausted
+// errors, and optionally limits its request rate. Zero fields use the
+// defaults: 5 attempts, a backoff with jitter from 250ms up to 5s, and a 30s
+// deadline per Sign call without a rate limit. The rate limit only throttles
+// the Sign calls of that Signer in the current process, so it is useful for
+// long-lived processes rather than for separate CI jobs sharing a quota.
+type KMSRetryConfig = kms.RetryConfig
+
 // NewSigner creates a Signer backed by Google Cloud KMS.
 // projectID, location, keyRingID, keyID, and version identify the CryptoKeyVersion.
-func NewSigner(ctx context.Context, projectID, location, keyRingID, keyID, version string) (*Signer, error) {
-	s, err := kms.NewSigner(ctx, projectID, location, keyRingID, keyID, version)
+// Pass "latest" as version to use the newest enabled RSA PKCS#1 SHA-256
+// version; KeyID then reports the resolved resource name. The public key is
+// fetched once to reject algorithms GitHub does not accept, and every
+// signature is verified with it before being returned. Both are skipped when
+// reading the public key is denied, as it is with roles/cloudkms.signer, which
+// Verified reports.
+//
+// opts are passed to the Cloud KMS client, e.g. option.WithEndpoint for an
+// emulator, or the options returned by WorkloadIdentityFederation or
+// ImpersonateServiceAccount. The GHAT_KMS_ENDPOINT environment variable has
+// the same effect without code changes.
+func NewSigner(ctx context.Context, projectID, location, keyRingID, keyID, version string, opts ...option.ClientOption) (*Signer, error) {
+	s, err := kms.NewSigner(ctx, projectID, location, keyRingID, keyID, version, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// WorkloadIdentityFederation returns a client option for NewSigner and
+// NewSignerFromResourceName that authenticates to Google Cloud with the GitHub
+// Actions OIDC token, exchanged at STS for the workload identity provider
+// (projects/NUMBER/locations/global/workloadIdentityPools/POOL/providers/PROVIDER).
+// When serviceAccount is not empty, it is impersonated. The job needs the
+// id-token: write permission.
+func WorkloadIdentityFederation(provider, serviceAccount string) (option.ClientOption, error) {
+	creds, err := wif.NewCredentials(wif.Config{
+		WorkloadIdentityProvider: provider,
+		ServiceAccount:           serviceAccount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return option.WithAuthCredentials(creds), nil
+}
+
+// ImpersonateServiceAccount returns a client option for NewSigner and
+// NewSignerFromResourceName that makes the KMS client use short-lived
+// credentials of target instead of the ambient Application Default
+// Credentials. The ambient identity needs roles/iam.serviceAccountTokenCreator
+// on the first of delegates, each delegate on the next, and the last on target.
+func ImpersonateServiceAccount(target string, delegates ...string) (option.ClientOption, error) {
+	creds, err := wif.Impersonate(wif.ImpersonateConfig{
+		TargetPrincipal: target,
+		Delegates:       delegates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return option.WithAuthCredentials(creds), nil
+}
+
+// NewSignerFromResourceName creates a Signer backed by Google Cloud KMS from a
+// full resource name, e.g.
+// "projects/my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key/cryptoKeyVersions/1".
+// A CryptoKey name without "/cryptoKeyVersions/N" signs with the newest enabled
+// RSA PKCS#1 SHA-256 version. Malformed names are rejected before any request
+// is made.
+func NewSignerFromResourceName(ctx context.Context, name string, opts ...option.ClientOption) (*Signer, error) {
+	s, err := kms.NewSignerFromResourceName(ctx, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewAWSKMSSigner creates a Signer backed by an AWS KMS asymmetric key.
+// keyID is a key ID, key ARN, alias name, or alias ARN of an RSA_2048 or
+// larger SIGN_VERIFY key. region overrides the region from the AWS
+// configuration; pass "" to use the default.
+func NewAWSKMSSigner(ctx context.Context, keyID, region string) (*Signer, error) {
+	s, err := awskms.NewSigner(ctx, keyID, region)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// VaultConfig identifies a HashiCorp Vault Transit key and how to authenticate to Vault.
+type VaultConfig struct {
+	// Address is the Vault server address, e.g. "https://vault.example.com:8200".
+	Address string
+	// Namespace is the Vault Enterprise namespace. Optional.
+	Namespace string
+
+	// Token is used to authenticate to Vault. When empty, RoleID and
+	// SecretID are used to log in with the AppRole auth method mounted at
+	// AppRoleMount (defaults to "approle").
+	Token        string
+	RoleID       string
+	SecretID     string
+	AppRoleMount string
+
+	// TransitMount is the mount path of the Transit secrets engine.
+	// Defaults to "transit".
+	TransitMount string
+	// KeyName is the name of an rsa-2048 or larger Transit key.
+	KeyName string
+	// KeyVersion pins the key version to sign with. Zero means the latest.
+	KeyVersion int
+}
+
+// NewVaultSigner creates a Signer backed by a HashiCorp Vault Transit key.
+// Signatures are requested with hash_algorithm=sha2-256 and
+// signature_algorithm=pkcs1v15. The key is read first to reject non-RSA key
+// types, which needs the read capability on <mount>/keys/<name>. If the token
+// was obtained with AppRole, Close revokes it.
+func NewVaultSigner(ctx context.Context, cfg VaultConfig) (*Signer, error) {
+	s, err := vault.NewSigner(ctx, vault.Config(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewAzureKeyVaultSigner creates a Signer backed by an Azure Key Vault RSA key,
+// authenticating with azidentity.DefaultAzureCredential.
+// vaultURL is e.g. "https://my-vault.vault.azure.net". Pass "" as keyVersion
+// to use the current version of the key.
+func NewAzureKeyVaultSigner(vaultURL, keyName, keyVersion string) (*Signer, error) {
+	s, err := azurekv.NewSigner(vaultURL, keyName, keyVersion)
 	if err != nil {
 		return nil, err
 	}
 	return &Signer{inner: s}, nil
 }
 
-// Close releases the underlying KMS client connection.
+// PKCS11Config identifies a PKCS#11 module, token, and RSA private key.
+type PKCS11Config struct {
+	// ModulePath is the path to the PKCS#11 shared library,
+	// e.g. "/usr/lib/softhsm/libsofthsm2.so".
+	ModulePath string
+	// SlotID selects the token by slot. Either SlotID or TokenLabel is required.
+	SlotID *uint
+	// TokenLabel selects the token by label.
+	TokenLabel string
+	// PIN is the user PIN of the token.
+	PIN string
+	// KeyLabel is the CKA_LABEL of the RSA private key.
+	KeyLabel string
+}
+
+// NewPKCS11Signer creates a Signer backed by an RSA key in a PKCS#11 token,
+// such as an HSM, signing with CKM_SHA256_RSA_PKCS. It requires cgo.
+// Close logs out of the token and unloads the module.
+func NewPKCS11Signer(cfg PKCS11Config) (*Signer, error) {
+	s, err := pkcs11.NewSigner(pkcs11.Config(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewSSHAgentSigner creates a Signer backed by an RSA key held by the ssh-agent
+// listening on socket, usually os.Getenv("SSH_AUTH_SOCK"). fingerprint selects
+// the key and may be an OpenSSH SHA256 or MD5 fingerprint, or the fingerprint
+// shown on the GitHub App settings page. Close closes the agent connection.
+func NewSSHAgentSigner(socket, fingerprint string) (*Signer, error) {
+	s, err := sshagent.NewSigner(socket, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewPrivateKeySigner creates a Signer backed by a PEM-encoded RSA private key,
+// such as the one downloaded from the GitHub App settings page.
+// passphrase is required only for encrypted PKCS#8 keys; pass nil otherwise.
+func NewPrivateKeySigner(pemBytes, passphrase []byte) (*Signer, error) {
+	s, err := privatekey.NewSigner(pemBytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewPrivateKeySignerFromFile creates a Signer backed by the PEM-encoded RSA
+// private key stored at path.
+func NewPrivateKeySignerFromFile(path string, passphrase []byte) (*Signer, error) {
+	s, err := privatekey.NewSignerFromFile(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: s}, nil
+}
+
+// NewFailoverSigner creates a Signer that signs with the first of signers that
+// does not fail with a retryable Cloud KMS error, such as an outage of its
+// location or a disabled CryptoKeyVersion. App.CreateGitHubAppToken also moves
+// on to the next signer when GitHub rejects the JWT, so listing the new and
+// the previous App key allows rotating it without downtime. The signers must
+// use the same algorithm. Close closes every signer that has a Close method.
+func NewFailoverSigner(signers ...JWTSigner) (*Signer, error) {
+	chain := make([]failover.Signer, len(signers))
+	for i, s := range signers {
+		chain[i] = s
+	}
+
+	c, err := failover.New(chain...)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{inner: c}, nil
+}
+
+// SetKMSRetryConfig replaces the retry settings of a Signer created with
+// NewSigner or NewSignerFromResourceName. It returns an error for other
+// signers, and must not be called concurrently with Sign.
+func (s *Signer) SetKMSRetryConfig(cfg KMSRetryConfig) error {
+	k, ok := s.inner.(*kms.Signer)
+	if !ok {
+		return fmt.Errorf("signer %s is not backed by Google Cloud KMS", s.KeyID())
+	}
+	k.SetRetryConfig(cfg)
+	return nil
+}
+
+// Verified reports whether the algorithm and the signatures of a Google Cloud
+// KMS key are checked with its public key. It is false when NewSigner or
+// NewSignerFromResourceName was denied reading the public key, and true for
+// every other Signer.
+func (s *Signer) Verified() bool {
+	k, ok := s.inner.(*kms.Signer)
+	return !ok || k.Unverified() == nil
+}
+
+// Sign signs data with the underlying key. It implements JWTSigner.
+func (s *Signer) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	return s.inner.Sign(ctx, data)
+}
+
+// Algorithm returns the JWS algorithm of the underlying key. It implements JWTSigner.
+func (s *Signer) Algorithm() string {
+	return s.inner.Algorithm()
+}
+
+// KeyID returns an identifier of the underlying key, such as the KMS
+// CryptoKeyVersion resource name. It implements JWTSigner.
+func (s *Signer) KeyID() string {
+	return s.inner.KeyID()
+}
+
+// Close releases the underlying signer resources, such as the KMS client connection.
 func (s *Signer) Close() error {
 	return s.inner.Close()
 }
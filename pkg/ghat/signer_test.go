@@ -0,0 +1,170 @@
+package ghat
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+
+	"github.com/yagihash/ghat/v2/internal/kms/kmstest"
+)
+
+var testKeyPath = filepath.Join("..", "..", "internal", "privatekey", "testdata", "rsa.pem")
+
+func testPublicKey(t *testing.T) *rsa.PublicKey {
+	t.Helper()
+	b, err := os.ReadFile(testKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(b)
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &key.PublicKey
+}
+
+// verifyJWT checks the RS256 signature of the bearer JWT in r.
+func verifyJWT(r *http.Request, pub *rsa.PublicKey) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+}
+
+func TestNewPrivateKeySigner(t *testing.T) {
+	pub := testPublicKey(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r, pub) {
+			jsonResponse(w, http.StatusUnauthorized, `{"message": "A JSON web token could not be decoded"}`)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+			jsonResponse(w, http.StatusOK, `{"id": 42}`)
+		case strings.Contains(r.URL.Path, "/access_tokens") && r.Method == http.MethodPost:
+			jsonResponse(w, http.StatusCreated, `{"token": "ghs_testtoken"}`)
+		}
+	}))
+	defer srv.Close()
+
+	signer, err := NewPrivateKeySignerFromFile(testKeyPath, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = signer.Close() }()
+
+	app := New("12345", signer, srv.URL)
+	got, err := app.CreateGitHubAppToken(context.Background(), "myorg", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ghs_testtoken" {
+		t.Errorf("token = %q, want %q", got, "ghs_testtoken")
+	}
+}
+
+func TestNewPrivateKeySigner_InvalidKey(t *testing.T) {
+	if _, err := NewPrivateKeySigner([]byte("not a key"), nil); err == nil {
+		t.Error("expected error but got nil")
+	}
+}
+
+func TestNewSignerFromResourceName_InvalidName(t *testing.T) {
+	names := []string{
+		"",
+		"my-key",
+		"projects/p/locations/global/keyRings/r",
+		"projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/first",
+	}
+
+	for _, name := range names {
+		if _, err := NewSignerFromResourceName(context.Background(), name); err == nil {
+			t.Errorf("NewSignerFromResourceName(%q): expected error but got nil", name)
+		}
+	}
+}
+
+func TestSigner_SetKMSRetryConfig_NotKMS(t *testing.T) {
+	signer, err := NewPrivateKeySignerFromFile(testKeyPath, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = signer.Close() }()
+
+	if err := signer.SetKMSRetryConfig(KMSRetryConfig{MaxAttempts: 3}); err == nil {
+		t.Error("expected error but got nil")
+	}
+}
+
+func TestSigner_Verified(t *testing.T) {
+	const name = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		denyPublicKey bool
+		want          bool
+	}{
+		{name: "public key readable", want: true},
+		{name: "public key denied", denyPublicKey: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := kmstest.NewServer(key)
+			defer srv.Close()
+			srv.AddVersion(name, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, kmspb.CryptoKeyVersion_ENABLED)
+			if tt.denyPublicKey {
+				srv.DenyPublicKey()
+			}
+
+			signer, err := NewSignerFromResourceName(context.Background(), name, srv.ClientOptions()...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() { _ = signer.Close() }()
+
+			if got := signer.Verified(); got != tt.want {
+				t.Errorf("Verified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("not kms", func(t *testing.T) {
+		signer, err := NewPrivateKeySignerFromFile(testKeyPath, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer func() { _ = signer.Close() }()
+
+		if !signer.Verified() {
+			t.Error("Verified() = false, want true")
+		}
+	})
+}